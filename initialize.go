@@ -21,17 +21,23 @@ func StartupContext(ctx context.Context, args *TLMInitialization) (context.Conte
 
 	//TODO: tracing
 
+	type setContext interface {
+		SetContextWrapper(ctx util.ContextWrapper)
+	}
+
 	logger, err := logging.InitLogging(args.Logging)
 	if err != nil {
 		return context.Background(), err
 	}
-	breakdown.Log = logger
-
-	//TODO: metrics
-
 	if logger == nil {
 		return context.Background(), errors.New("initialization args empty")
 	}
+	if _, ok := logger.(setContext); !ok {
+		logger = logging.WrapLogger(logger)
+	}
+	breakdown.Log = logger
+
+	//TODO: metrics
 
 	/* Result is effectivly:
 	ContextWrapper {
@@ -47,16 +53,7 @@ func StartupContext(ctx context.Context, args *TLMInitialization) (context.Conte
 		Ctx: contextWithStruct(ctx, breakdown),
 	}
 
-	type setContext interface {
-		SetContextWrapper(ctx util.ContextWrapper)
-	}
-	if breakdown.Log != nil {
-		if setCtx, ok := breakdown.Log.(setContext); ok {
-			setCtx.SetContextWrapper(tlmCtxWrapper)
-		} else {
-			return nil, errors.New("internal error: unknown logger")
-		}
-	}
+	logger.(setContext).SetContextWrapper(tlmCtxWrapper)
 
 	return tlmCtxWrapper.GetContext(), nil
 }
@@ -2,15 +2,26 @@ package logging
 
 import (
 	"context"
+	"math"
 	"os"
 
 	"github.com/rcmaniac25/tlm/util"
 )
 
-type nullLoggerType struct{}
+type nullLoggerType struct {
+	// Used instead of os.Exit and panic when set
+	exitFunc  func(int)
+	panicFunc func(any)
+}
 
 var NullLogger = nullLoggerType{}
 
+type panicOnlyDebugModeType struct{}
+
+// Pass as a value to WithField(s) so Panic-level logs only panic when TLMLoggingInitialization.DebugMode is set.
+// Otherwise they are logged at the error level along with the stack of the caller. The field itself is not logged.
+var PanicOnlyDebugMode = panicOnlyDebugModeType{}
+
 func (n *nullLoggerType) Context() context.Context {
 	return context.Background()
 }
@@ -21,31 +32,112 @@ func (n *nullLoggerType) Context() context.Context {
 type selfReferentialLogger struct {
 	TLMContext util.ContextWrapper
 	LoggerImpl Logger
+
+	settings       *loggerSettings
+	panicOnlyDebug bool
+}
+
+// Settings that are shared between a logger and every logger derived from it
+type loggerSettings struct {
+	// Trace logs are only passed to the implementation when explicitly requested. This way implementations without a trace level
+	// can simply log them at their lowest level without them showing up when only debug logs were requested.
+	traceEnabled bool
+	verbosity    int
+
+	errorKey          string
+	captureErrorStack bool
+
+	debugMode bool
+
+	exitFunc  func(int)
+	panicFunc func(any)
+}
+
+func newLoggerSettings(args *TLMLoggingInitialization) *loggerSettings {
+	return &loggerSettings{
+		traceEnabled: args.Level == TraceLevel,
+		verbosity:    args.Verbosity,
+
+		errorKey:          args.Formatter.errorKey(),
+		captureErrorStack: args.CaptureErrorStack,
+
+		debugMode: args.DebugMode,
+
+		exitFunc:  args.ExitFunc,
+		panicFunc: args.PanicFunc,
+	}
 }
 
 func (s *selfReferentialLogger) SetContextWrapper(ctx util.ContextWrapper) {
 	s.TLMContext = ctx
 }
 
+// Loggers that weren't set up by tlm.Startup use context.Background()
 func (s *selfReferentialLogger) Context() context.Context {
+	if s.TLMContext == nil {
+		return context.Background()
+	}
 	return s.TLMContext.GetContext()
 }
 
-func (s *selfReferentialLogger) updateLogger(update func() Logger) Logger {
+// Get a TLMLogger for any Logger, such as one that wraps a TLMLogger. Loggers from InitLogging are returned as they are
+func WrapLogger(logger Logger) TLMLogger {
+	if refLogger, ok := logger.(*selfReferentialLogger); ok {
+		return refLogger
+	}
+	return &selfReferentialLogger{
+		LoggerImpl: logger,
+		settings: &loggerSettings{
+			// The wrapped logger decides what's logged
+			traceEnabled: logger.Enabled(TraceLevel),
+			verbosity:    math.MaxInt,
+			errorKey:     Formatter{}.errorKey(),
+		},
+	}
+}
+
+func (s *selfReferentialLogger) updateLogger(update func(refLogger *selfReferentialLogger)) Logger {
 	type UpdateLogger interface {
 		UpdateLogger(logger TLMLogger) util.ContextWrapper
 	}
 
 	refLogger := &selfReferentialLogger{
-		LoggerImpl: update(),
+		LoggerImpl:     s.LoggerImpl,
+		settings:       s.settings,
+		panicOnlyDebug: s.panicOnlyDebug,
 	}
+	update(refLogger)
 	if updateLogger, ok := s.TLMContext.(UpdateLogger); ok {
 		refLogger.TLMContext = updateLogger.UpdateLogger(refLogger)
-		return refLogger
 	}
-	return s // Simply ignore the field since we got an invalid type...
+	return refLogger
+}
+
+// Call a panic log function. When PanicFunc is set, the panic is recovered and passed to it
+func (s *selfReferentialLogger) callPanic(log func()) {
+	if s.settings.panicFunc == nil {
+		log()
+		return
+	}
+	panicked := true
+	defer func() {
+		if panicked {
+			s.settings.panicFunc(unwrapPanicValue(recover()))
+		}
+	}()
+	log()
+	panicked = false
+}
+
+// Get the logger to use for panics. When marked with PanicOnlyDebugMode and not in debug mode, panics are logged as errors with a stack
+func (s *selfReferentialLogger) panicLogger() (logger Logger, shouldPanic bool) {
+	if !s.panicOnlyDebug || s.settings.debugMode {
+		return s.LoggerImpl, true
+	}
+	return s.LoggerImpl.WithField(ErrorStackKey, callerStack()), false
 }
 
+// Deprecated: set TLMLoggingInitialization.ExitFunc instead, which also works with NullLogger and custom loggers
 func (s *selfReferentialLogger) TestingSetFatalExitFunction(exitHandler func(int)) bool {
 	type InternalTestingExitHandler interface {
 		testExitFunc(exitHandler func(int)) bool
@@ -56,14 +148,64 @@ func (s *selfReferentialLogger) TestingSetFatalExitFunction(exitHandler func(int
 	return false
 }
 
+// Dispatch a log at a runtime-picked level to the matching level function
+func logAtLevel(logger Logger, level LogLevel, args ...any) {
+	switch level {
+	case TraceLevel:
+		logger.Trace(args...)
+	case DebugLevel:
+		logger.Debug(args...)
+	case InfoLevel:
+		logger.Info(args...)
+	case WarnLevel:
+		logger.Warn(args...)
+	case ErrorLevel:
+		logger.Error(args...)
+	case PanicLevel:
+		logger.Panic(args...)
+	case FatalLevel:
+		logger.Fatal(args...)
+	}
+}
+
+func logfAtLevel(logger Logger, level LogLevel, format string, args ...any) {
+	switch level {
+	case TraceLevel:
+		logger.Tracef(format, args...)
+	case DebugLevel:
+		logger.Debugf(format, args...)
+	case InfoLevel:
+		logger.Infof(format, args...)
+	case WarnLevel:
+		logger.Warnf(format, args...)
+	case ErrorLevel:
+		logger.Errorf(format, args...)
+	case PanicLevel:
+		logger.Panicf(format, args...)
+	case FatalLevel:
+		logger.Fatalf(format, args...)
+	}
+}
+
 // All the builtin functions
 
 func (n *nullLoggerType) WithField(key string, value any) Logger {
 	return n
 }
+func (s *selfReferentialLogger) WithCallerSkip(skip int) Logger {
+	return s.updateLogger(func(refLogger *selfReferentialLogger) {
+		refLogger.LoggerImpl = WithCallerSkip(s.LoggerImpl, skip)
+	})
+}
+
 func (s *selfReferentialLogger) WithField(key string, value any) Logger {
-	return s.updateLogger(func() Logger {
-		return s.LoggerImpl.WithField(key, value)
+	if value == PanicOnlyDebugMode {
+		return s.updateLogger(func(refLogger *selfReferentialLogger) {
+			refLogger.panicOnlyDebug = true
+		})
+	}
+	return s.updateLogger(func(refLogger *selfReferentialLogger) {
+		refLogger.LoggerImpl = s.LoggerImpl.WithField(key, value)
 	})
 }
 
@@ -71,11 +213,110 @@ func (n *nullLoggerType) WithFields(fields util.Fields) Logger {
 	return n
 }
 func (s *selfReferentialLogger) WithFields(fields util.Fields) Logger {
-	return s.updateLogger(func() Logger {
-		return s.LoggerImpl.WithFields(fields)
+	panicOnlyDebug := false
+	for _, value := range fields {
+		if value == PanicOnlyDebugMode {
+			panicOnlyDebug = true
+			break
+		}
+	}
+	if panicOnlyDebug {
+		// Don't modify the fields that were passed in
+		implFields := make(util.Fields)
+		for key, value := range fields {
+			if value != PanicOnlyDebugMode {
+				implFields[key] = value
+			}
+		}
+		fields = implFields
+	}
+	return s.updateLogger(func(refLogger *selfReferentialLogger) {
+		refLogger.LoggerImpl = s.LoggerImpl.WithFields(fields)
+		refLogger.panicOnlyDebug = refLogger.panicOnlyDebug || panicOnlyDebug
 	})
 }
 
+func (n *nullLoggerType) WithError(err error) Logger {
+	return n
+}
+func (s *selfReferentialLogger) WithError(err error) Logger {
+	if err == nil {
+		return s
+	}
+	return s.WithFields(errorFields(err, s.settings.errorKey, s.settings.captureErrorStack))
+}
+
+func (n *nullLoggerType) V(level int) Logger {
+	return n
+}
+func (s *selfReferentialLogger) V(level int) Logger {
+	if level > s.settings.verbosity {
+		return &nullLoggerType{exitFunc: s.settings.exitFunc, panicFunc: s.settings.panicFunc}
+	}
+	return s
+}
+
+func (n *nullLoggerType) Log(level LogLevel, args ...any) {
+	logAtLevel(n, level, args...)
+}
+func (s *selfReferentialLogger) Log(level LogLevel, args ...any) {
+	switch level {
+	case TraceLevel:
+		if !s.settings.traceEnabled {
+			return
+		}
+	case PanicLevel:
+		s.Panic(args...)
+		return
+	}
+	s.LoggerImpl.Log(level, args...)
+}
+
+func (n *nullLoggerType) Logf(level LogLevel, format string, args ...any) {
+	logfAtLevel(n, level, format, args...)
+}
+func (s *selfReferentialLogger) Logf(level LogLevel, format string, args ...any) {
+	switch level {
+	case TraceLevel:
+		if !s.settings.traceEnabled {
+			return
+		}
+	case PanicLevel:
+		s.Panicf(format, args...)
+		return
+	}
+	s.LoggerImpl.Logf(level, format, args...)
+}
+
+func (n *nullLoggerType) Enabled(level LogLevel) bool {
+	return false
+}
+func (s *selfReferentialLogger) Enabled(level LogLevel) bool {
+	if level == TraceLevel && !s.settings.traceEnabled {
+		return false
+	}
+	return s.LoggerImpl.Enabled(level)
+}
+
+func (n *nullLoggerType) Tracef(format string, args ...any) {}
+func (n *nullLoggerType) Trace(args ...any)                 {}
+func (n *nullLoggerType) Traceln(args ...any)               {}
+func (s *selfReferentialLogger) Tracef(format string, args ...any) {
+	if s.settings.traceEnabled {
+		s.LoggerImpl.Tracef(format, args...)
+	}
+}
+func (s *selfReferentialLogger) Trace(args ...any) {
+	if s.settings.traceEnabled {
+		s.LoggerImpl.Trace(args...)
+	}
+}
+func (s *selfReferentialLogger) Traceln(args ...any) {
+	if s.settings.traceEnabled {
+		s.LoggerImpl.Traceln(args...)
+	}
+}
+
 func (n *nullLoggerType) Debugf(format string, args ...any) {}
 func (n *nullLoggerType) Debug(args ...any)                 {}
 func (n *nullLoggerType) Debugln(args ...any)               {}
@@ -128,22 +369,61 @@ func (s *selfReferentialLogger) Errorln(args ...any) {
 	s.LoggerImpl.Errorln(args...)
 }
 
-func (n *nullLoggerType) Panicf(format string, args ...any) { panic("Panicf") }
-func (n *nullLoggerType) Panic(args ...any)                 { panic("Panic") }
-func (n *nullLoggerType) Panicln(args ...any)               { panic("Panicln") }
+func (n *nullLoggerType) panic(value any) {
+	if n.panicFunc != nil {
+		n.panicFunc(value)
+		return
+	}
+	panic(value)
+}
+func (n *nullLoggerType) Panicf(format string, args ...any) { n.panic("Panicf") }
+func (n *nullLoggerType) Panic(args ...any)                 { n.panic("Panic") }
+func (n *nullLoggerType) Panicln(args ...any)               { n.panic("Panicln") }
 func (s *selfReferentialLogger) Panicf(format string, args ...any) {
-	s.LoggerImpl.Panicf(format, args...)
+	if logger, shouldPanic := s.panicLogger(); shouldPanic {
+		s.callPanic(func() { logger.Panicf(format, args...) })
+	} else {
+		logger.Errorf(format, args...)
+	}
 }
 func (s *selfReferentialLogger) Panic(args ...any) {
-	s.LoggerImpl.Panic(args...)
+	if logger, shouldPanic := s.panicLogger(); shouldPanic {
+		s.callPanic(func() { logger.Panic(args...) })
+	} else {
+		logger.Error(args...)
+	}
 }
 func (s *selfReferentialLogger) Panicln(args ...any) {
-	s.LoggerImpl.Panicln(args...)
+	if logger, shouldPanic := s.panicLogger(); shouldPanic {
+		s.callPanic(func() { logger.Panicln(args...) })
+	} else {
+		logger.Errorln(args...)
+	}
+}
+
+func (n *nullLoggerType) exit() {
+	if n.exitFunc != nil {
+		n.exitFunc(1)
+		return
+	}
+	os.Exit(1)
+}
+func (n *nullLoggerType) WithExitFunc(exitFunc func(int)) Logger {
+	return &nullLoggerType{exitFunc: exitFunc, panicFunc: n.panicFunc}
+}
+
+// Nothing is logged, so there's no caller to skip
+func (n *nullLoggerType) WithCallerSkip(skip int) Logger {
+	return n
 }
 
-func (n *nullLoggerType) Fatalf(format string, args ...any) { os.Exit(1) }
-func (n *nullLoggerType) Fatal(args ...any)                 { os.Exit(1) }
-func (n *nullLoggerType) Fatalln(args ...any)               { os.Exit(1) }
+// Nothing is logged, so there's nothing for hooks to do
+func (n *nullLoggerType) WithHooks(hooks []Hook) Logger {
+	return n
+}
+func (n *nullLoggerType) Fatalf(format string, args ...any) { n.exit() }
+func (n *nullLoggerType) Fatal(args ...any)                 { n.exit() }
+func (n *nullLoggerType) Fatalln(args ...any)               { n.exit() }
 func (s *selfReferentialLogger) Fatalf(format string, args ...any) {
 	s.LoggerImpl.Fatalf(format, args...)
 }
@@ -153,3 +433,63 @@ func (s *selfReferentialLogger) Fatal(args ...any) {
 func (s *selfReferentialLogger) Fatalln(args ...any) {
 	s.LoggerImpl.Fatalln(args...)
 }
+
+// Makes ExitFunc work with loggers that don't implement ExitFuncLogger. Fatal logs are written as errors, since the
+// logger would exit otherwise, then ExitFunc is called
+type exitFuncPolyfill struct {
+	Logger
+	exitFunc func(int)
+}
+
+func (e *exitFuncPolyfill) wrap(logger Logger) Logger {
+	return &exitFuncPolyfill{Logger: logger, exitFunc: e.exitFunc}
+}
+
+func (e *exitFuncPolyfill) WithField(key string, value any) Logger {
+	return e.wrap(e.Logger.WithField(key, value))
+}
+func (e *exitFuncPolyfill) WithFields(fields util.Fields) Logger {
+	return e.wrap(e.Logger.WithFields(fields))
+}
+func (e *exitFuncPolyfill) WithError(err error) Logger {
+	return e.wrap(e.Logger.WithError(err))
+}
+func (e *exitFuncPolyfill) V(level int) Logger {
+	return e.wrap(e.Logger.V(level))
+}
+func (e *exitFuncPolyfill) WithCallerSkip(skip int) Logger {
+	return e.wrap(WithCallerSkip(e.Logger, skip))
+}
+func (e *exitFuncPolyfill) withHookResult(result *hookResult) Logger {
+	return e.wrap(withHookResult(e.Logger, result))
+}
+func (e *exitFuncPolyfill) WithExitFunc(exitFunc func(int)) Logger {
+	return &exitFuncPolyfill{Logger: e.Logger, exitFunc: exitFunc}
+}
+
+func (e *exitFuncPolyfill) Log(level LogLevel, args ...any) {
+	if level != FatalLevel {
+		e.Logger.Log(level, args...)
+		return
+	}
+	e.Fatal(args...)
+}
+func (e *exitFuncPolyfill) Logf(level LogLevel, format string, args ...any) {
+	if level != FatalLevel {
+		e.Logger.Logf(level, format, args...)
+		return
+	}
+	e.Fatalf(format, args...)
+}
+func (e *exitFuncPolyfill) Fatalf(format string, args ...any) {
+	e.Logger.Errorf(format, args...)
+	e.exitFunc(1)
+}
+func (e *exitFuncPolyfill) Fatal(args ...any) {
+	e.Logger.Error(args...)
+	e.exitFunc(1)
+}
+func (e *exitFuncPolyfill) Fatalln(args ...any) {
+	e.Logger.Errorln(args...)
+	e.exitFunc(1)
+}
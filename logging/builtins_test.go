@@ -1,9 +1,13 @@
 package logging_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/rcmaniac25/tlm"
 	"github.com/rcmaniac25/tlm/logging"
@@ -44,20 +48,19 @@ func getLoggers() []BuiltinLogger {
 			logGenerated: func() (logging.TLMLogger, *logging.DebugLogCollector, bool) {
 				inits := new(tlm.TLMInitialization)
 				inits.Logging = new(logging.TLMLoggingInitialization)
+				inits.Logging.Type = logging.LogrusLogType
+				inits.Logging.Output = io.Discard
 
 				collector := logging.NewDebugLogCollector()
 				collector.SetupInitialization(inits.Logging)
-				inits.Logging.Level = logging.DebugLevel
+				inits.Logging.Level = logging.TraceLevel
 
 				ctx, err := tlm.Startup(inits)
 				if err != nil {
 					return nil, nil, false
 				}
 
-				logger := tlm.Log(ctx)
-				exitHandlerSet := ReplaceExitHandler(logger, collector.OnExitCode)
-
-				return logger, collector, exitHandlerSet
+				return tlm.Log(ctx), collector, true
 			},
 			logMapper: func(tlmLogLevel logging.LogLevel) string {
 				if tlmLogLevel == logging.WarnLevel {
@@ -66,6 +69,24 @@ func getLoggers() []BuiltinLogger {
 				return tlmLogLevel.String()
 			},
 		},
+		{
+			name: "Sink",
+			logGenerated: func() (logging.TLMLogger, *logging.DebugLogCollector, bool) {
+				inits := new(tlm.TLMInitialization)
+				inits.Logging = new(logging.TLMLoggingInitialization)
+
+				collector := logging.NewDebugLogCollector()
+				collector.SetupInitialization(inits.Logging)
+				inits.Logging.Level = logging.TraceLevel
+
+				ctx, err := tlm.Startup(inits)
+				if err != nil {
+					return nil, nil, false
+				}
+
+				return tlm.Log(ctx), collector, true
+			},
+		},
 	}
 	results := make([]BuiltinLogger, 0)
 	for _, logger := range loggers {
@@ -108,10 +129,10 @@ func TestWithField(t *testing.T) {
 				util.AssertEqual(t, logItem.Collector.GetNumberLogs(), 2, "count")
 
 				util.AssertEqual(t, logItem.Collector.GetMessage(0), "Test", "message")
-				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(0, "myField"), float64(1234), "field")
+				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(0, "myField"), 1234, "field")
 
 				util.AssertEqual(t, logItem.Collector.GetMessage(1), "Test2", "message")
-				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "myField"), float64(1234), "field")
+				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "myField"), 1234, "field")
 				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "theOtherOne"), "soup", "field")
 			}
 		})
@@ -138,13 +159,15 @@ func TestWithFields(t *testing.T) {
 				util.AssertEqual(t, logItem.Collector.GetNumberLogs(), 2, "count")
 
 				util.AssertEqual(t, logItem.Collector.GetMessage(0), "Test", "message")
-				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(0, "myField"), float64(6547), "field")
+				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(0, "myField"), 6547, "field")
 				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(0, "hi"), "people", "field")
 
 				util.AssertEqual(t, logItem.Collector.GetMessage(1), "Test", "message")
-				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "myField"), float64(6547), "field")
+				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "myField"), 6547, "field")
 				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "hi"), "people", "field")
-				util.AssertEqualExistsFunc(t, logItem.Collector.GetFieldFunc(1, "reason"), "Ohai there", "field")
+				reason, ok := logItem.Collector.GetField(1, "reason")
+				util.AssertEqual(t, ok, true, "field exists")
+				util.AssertEqual(t, reason.(error).Error(), "Ohai there", "field")
 			}
 		})
 	}
@@ -165,6 +188,44 @@ func TestLoggingFunctions(t *testing.T) {
 		args     args
 		expected expected
 	}{
+		{
+			name: "Trace",
+			args: args{
+				logFunc:    func(logger logging.Logger) { logger.Trace("TraceTest", 123, float64(10.123), "Value") },
+				fieldValue: "TraceField",
+				willPanic:  false,
+			},
+			expected: expected{
+				msg:   "TraceTest123 10.123Value",
+				level: logging.TraceLevel,
+			},
+		},
+		{
+			name: "Tracef",
+			args: args{
+				logFunc: func(logger logging.Logger) {
+					logger.Tracef("%s-%d--%.3f---%s", "TraceTest", 123, float64(10.123), "Value")
+				},
+				fieldValue: "TraceField",
+				willPanic:  false,
+			},
+			expected: expected{
+				msg:   "TraceTest-123--10.123---Value",
+				level: logging.TraceLevel,
+			},
+		},
+		{
+			name: "Traceln",
+			args: args{
+				logFunc:    func(logger logging.Logger) { logger.Traceln("TraceTest", 123, float64(10.123), "Value") },
+				fieldValue: "TraceField",
+				willPanic:  false,
+			},
+			expected: expected{
+				msg:   "TraceTest 123 10.123 Value",
+				level: logging.TraceLevel,
+			},
+		},
 		{
 			name: "Debug",
 			args: args{
@@ -355,6 +416,44 @@ func TestLoggingFunctions(t *testing.T) {
 				level: logging.PanicLevel,
 			},
 		},
+		{
+			name: "Log",
+			args: args{
+				logFunc:    func(logger logging.Logger) { logger.Log(logging.WarnLevel, "LogTest", 123, float64(10.123), "Value") },
+				fieldValue: "LogField",
+				willPanic:  false,
+			},
+			expected: expected{
+				msg:   "LogTest123 10.123Value",
+				level: logging.WarnLevel,
+			},
+		},
+		{
+			name: "Logf",
+			args: args{
+				logFunc: func(logger logging.Logger) {
+					logger.Logf(logging.TraceLevel, "%s-%d--%.3f---%s", "LogTest", 123, float64(10.123), "Value")
+				},
+				fieldValue: "LogField",
+				willPanic:  false,
+			},
+			expected: expected{
+				msg:   "LogTest-123--10.123---Value",
+				level: logging.TraceLevel,
+			},
+		},
+		{
+			name: "Log Panic",
+			args: args{
+				logFunc:    func(logger logging.Logger) { logger.Log(logging.PanicLevel, "LogTest", 123, float64(10.123), "Value") },
+				fieldValue: "LogField",
+				willPanic:  true,
+			},
+			expected: expected{
+				msg:   "LogTest123 10.123Value",
+				level: logging.PanicLevel,
+			},
+		},
 		{
 			name: "Fatal",
 			args: args{
@@ -448,3 +547,292 @@ func TestLoggingFunctions(t *testing.T) {
 		})
 	}
 }
+
+func TestTraceNotEnabled(t *testing.T) {
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = new(logging.TLMLoggingInitialization)
+
+	collector := logging.NewDebugLogCollector()
+	collector.SetupInitialization(inits.Logging)
+	inits.Logging.Level = logging.DebugLevel
+
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	tlm.Log(ctx).Trace("wire bytes")
+	tlm.Log(ctx).Debug("Debug")
+	util.AssertEqual(t, collector.GetNumberLogs(), 1, "count")
+	util.AssertEqual(t, collector.GetMessage(0), "Debug", "message")
+}
+
+func TestVerbosity(t *testing.T) {
+	for _, logItem := range getLoggers() {
+		t.Run(logItem.Name, func(t *testing.T) {
+			util.AssertNoPanic(t, func() {
+				logItem.Logger.V(1).Info("Too verbose")
+				logItem.Logger.V(0).Info("Not verbose")
+			}, "log")
+			if logItem.Collector != nil {
+				util.AssertEqual(t, logItem.Collector.GetNumberLogs(), 1, "count")
+				util.AssertEqual(t, logItem.Collector.GetMessage(0), "Not verbose", "message")
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	for _, logItem := range getLoggers() {
+		t.Run(logItem.Name, func(t *testing.T) {
+			// Loggers with a collector log everything
+			enabled := logItem.Collector != nil
+			for _, level := range []logging.LogLevel{logging.TraceLevel, logging.DebugLevel, logging.InfoLevel, logging.WarnLevel, logging.ErrorLevel, logging.PanicLevel, logging.FatalLevel} {
+				util.AssertEqualf(t, logItem.Logger.Enabled(level), enabled, "%s enabled", level)
+			}
+			util.AssertEqual(t, logItem.Logger.Enabled(logging.DefaultLevel), false, "default enabled")
+		})
+	}
+}
+
+func TestPanicOnlyDebugMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		debugMode bool
+		logFunc   func(logging.Logger)
+	}{
+		{
+			name:      "WithField",
+			debugMode: false,
+			logFunc:   func(logger logging.Logger) { logger.WithField("assert", logging.PanicOnlyDebugMode).Panic("Assert") },
+		},
+		{
+			name:      "WithFields",
+			debugMode: false,
+			logFunc: func(logger logging.Logger) {
+				logger.WithFields(util.Fields{"assert": logging.PanicOnlyDebugMode, "myField": "value"}).Panicf("%s", "Assert")
+			},
+		},
+		{
+			name:      "Derived",
+			debugMode: false,
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("assert", logging.PanicOnlyDebugMode).WithField("myField", "value").Log(logging.PanicLevel, "Assert")
+			},
+		},
+		{
+			name:      "Debug Mode",
+			debugMode: true,
+			logFunc:   func(logger logging.Logger) { logger.WithField("assert", logging.PanicOnlyDebugMode).Panicln("Assert") },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = new(logging.TLMLoggingInitialization)
+
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			inits.Logging.DebugMode = test.debugMode
+
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			if test.debugMode {
+				util.AssertPanic(t, func() { test.logFunc(tlm.Log(ctx)) }, "debug mode")
+				util.AssertEqual(t, collector.GetLogLevel(0), logging.PanicLevel, "level")
+				return
+			}
+
+			util.AssertNoPanic(t, func() { test.logFunc(tlm.Log(ctx)) }, "production mode")
+			util.AssertEqual(t, collector.GetNumberLogs(), 1, "count")
+			util.AssertEqual(t, collector.GetMessage(0), "Assert", "message")
+			util.AssertEqual(t, collector.GetLogLevel(0), logging.ErrorLevel, "level")
+
+			assertField, _ := collector.GetField(0, "assert")
+			util.AssertEqual(t, assertField, nil, "marker field")
+			stack, _ := collector.GetField(0, logging.ErrorStackKey)
+			stackStr, _ := stack.(string)
+			util.AssertContains(t, stackStr, "TestPanicOnlyDebugMode", "stack")
+		})
+	}
+}
+
+func TestPanicOnlyDebugModeNotSet(t *testing.T) {
+	for _, logItem := range getLoggers() {
+		t.Run(logItem.Name, func(t *testing.T) {
+			util.AssertPanic(t, func() {
+				logItem.Logger.Panic("Not marked")
+			}, "panic")
+		})
+	}
+}
+
+func TestClock(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	clock := util.ClockFunc(func() time.Time {
+		return now
+	})
+
+	for _, logType := range []logging.LogType{logging.LogrusLogType, logging.SinkLogType} {
+		t.Run(logType.String(), func(t *testing.T) {
+			output := new(bytes.Buffer)
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{
+				Type:      logType,
+				Output:    output,
+				Clock:     clock,
+				Formatter: logging.Formatter{Type: logging.LogfmtFormat, LevelKey: "-"},
+			}
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			start := now
+			now = now.Add(time.Second)
+			tlm.Log(ctx).Info("First")
+			now = now.Add(time.Second)
+			tlm.Log(ctx).WithField("n", 2).Info("Second")
+
+			util.AssertEqual(t, collector.GetTime(0), start.Add(time.Second), "first time")
+			util.AssertEqual(t, collector.GetTime(1), start.Add(2*time.Second), "second time")
+			util.AssertEqual(t, output.String(), fmt.Sprintf("time=%s msg=First\ntime=%s msg=Second n=2\n",
+				start.Add(time.Second).Format(time.RFC3339), start.Add(2*time.Second).Format(time.RFC3339)), "output")
+		})
+	}
+}
+
+func TestExitFunc(t *testing.T) {
+	tests := []struct {
+		name  string
+		inits logging.TLMLoggingInitialization
+	}{
+		{
+			name:  "Logrus",
+			inits: logging.TLMLoggingInitialization{Type: logging.LogrusLogType, Output: io.Discard},
+		},
+		{
+			name:  "Sink",
+			inits: logging.TLMLoggingInitialization{Type: logging.SinkLogType},
+		},
+		{
+			name:  "Tee",
+			inits: logging.TLMLoggingInitialization{Type: logging.LogrusLogType, Output: io.Discard, Sinks: []logging.Sink{logging.NewDebugLogCollector()}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &test.inits
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			inits.Logging.Level = logging.TraceLevel
+
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			logger := tlm.Log(ctx).WithField("derived", true)
+			logger.Fatal("Derived")
+			logger.WithField("again", 2).Fatalf("Derived %d", 2)
+			logger.V(1).Fatal("Too verbose")
+
+			util.AssertEqual(t, collector.GetNumberLogs(), 2, "count")
+			util.AssertEqualExistsFunc(t, collector.GetFatalExitcodeFunc(0), 1, "first exit code")
+			util.AssertEqualExistsFunc(t, collector.GetFatalExitcodeFunc(1), 1, "second exit code")
+		})
+	}
+}
+
+func TestExitFuncNullLogger(t *testing.T) {
+	util.AssertNoError(t, logging.RegisterLogger("ExitNullLogger", func(_ *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		return &logging.NullLogger, nil
+	}), "register")
+	defer logging.UnregisterLogger("ExitNullLogger")
+
+	exitcodes := make([]int, 0)
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:        logging.CustomLogType,
+		CustomeType: "ExitNullLogger",
+		ExitFunc:    func(code int) { exitcodes = append(exitcodes, code) },
+	}
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	tlm.Log(ctx).Fatal("Null")
+	tlm.Log(ctx).WithField("derived", true).Fatalln("Derived")
+	tlm.Log(ctx).V(1).Fatalf("%s", "Too verbose")
+	util.AssertEqual(t, fmt.Sprint(exitcodes), "[1 1 1]", "exit codes")
+}
+
+// Loggers that don't implement ExitFuncLogger still work with the collector
+func TestExitFuncPolyfill(t *testing.T) {
+	type exitlessLogger struct {
+		logging.Logger
+	}
+	output := new(bytes.Buffer)
+	util.AssertNoError(t, logging.RegisterLogger("ExitlessLogger", func(args *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		logger, err := logging.InitLogrus(&logging.TLMLoggingInitialization{Output: output, Formatter: args.Formatter})
+		return exitlessLogger{Logger: logger}, err
+	}), "register")
+	defer logging.UnregisterLogger("ExitlessLogger")
+
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:        logging.CustomLogType,
+		CustomeType: "ExitlessLogger",
+		Formatter:   logging.Formatter{Type: logging.LogfmtFormat, TimeKey: "-"},
+	}
+	collector := logging.NewDebugLogCollector()
+	collector.SetupInitialization(inits.Logging)
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	tlm.Log(ctx).WithField("derived", true).Fatal("Fatal")
+	tlm.Log(ctx).Logf(logging.FatalLevel, "Fatal %d", 2)
+
+	util.AssertEqual(t, collector.GetNumberLogs(), 2, "count")
+	for i := 0; i < 2; i++ {
+		util.AssertEqualf(t, collector.GetLogLevel(i), logging.FatalLevel, "level %d", i)
+		exitcode, ok := collector.GetFatalExitcode(i)
+		util.AssertEqualf(t, ok, true, "exited %d", i)
+		util.AssertEqualf(t, exitcode, 1, "exit code %d", i)
+	}
+	// Fatal logs are written as errors, so the logger doesn't exit
+	util.AssertEqual(t, output.String(), "level=error msg=Fatal derived=true\nlevel=error msg=\"Fatal 2\"\n", "output")
+}
+
+func TestPanicFunc(t *testing.T) {
+	for _, logType := range []logging.LogType{logging.LogrusLogType, logging.SinkLogType} {
+		t.Run(logType.String(), func(t *testing.T) {
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{Type: logType, Output: io.Discard}
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			inits.Logging.PanicFunc = collector.OnPanic
+
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			util.AssertNoPanic(t, func() {
+				tlm.Log(ctx).WithField("derived", true).Panicf("Panic %d", 1)
+				tlm.Log(ctx).Log(logging.PanicLevel, "Panic ", 2)
+			}, "panic")
+			util.AssertEqual(t, collector.GetNumberLogs(), 2, "count")
+			util.AssertEqualExistsFunc(t, collector.GetPanicValueFunc(0), "Panic 1", "first panic value")
+			util.AssertEqualExistsFunc(t, collector.GetPanicValueFunc(1), "Panic 2", "second panic value")
+		})
+	}
+}
+
+func TestTestingSetFatalExitFunctionDerived(t *testing.T) {
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{Type: logging.LogrusLogType, Output: io.Discard}
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	exitcode := 0
+	logger := tlm.Log(ctx).WithField("derived", true)
+	util.AssertEqual(t, ReplaceExitHandler(logger, func(code int) { exitcode = code }), true, "exit handler set")
+	logger.Fatal("Derived")
+	util.AssertEqual(t, exitcode, 1, "exit code")
+}
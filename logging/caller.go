@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Packages skipped when finding the caller, along with this package and logrus
+var (
+	callerSkipPackagesLock sync.RWMutex
+	callerSkipPackages     = make(map[string]bool)
+)
+
+// Skip a package when finding the caller, such as a package of logging helpers. The package is its full import path
+func RegisterCallerSkipPackage(pkg string) {
+	callerSkipPackagesLock.Lock()
+	defer callerSkipPackagesLock.Unlock()
+	callerSkipPackages[pkg] = true
+}
+
+func UnregisterCallerSkipPackage(pkg string) {
+	callerSkipPackagesLock.Lock()
+	defer callerSkipPackagesLock.Unlock()
+	delete(callerSkipPackages, pkg)
+}
+
+func skipCallerPackage(pkg string) bool {
+	if pkg == LoggingPackageName || pkg == LogrusPackageName {
+		return true
+	}
+	callerSkipPackagesLock.RLock()
+	defer callerSkipPackagesLock.RUnlock()
+	return callerSkipPackages[pkg]
+}
+
+// Loggers that can report a caller further up the stack. Custom loggers implement this to support WithCallerSkip
+type CallerSkipLogger interface {
+	// Get a logger that skips more frames when finding the caller, as do all loggers derived from it
+	WithCallerSkip(skip int) Logger
+}
+
+// Get a logger that reports the caller skip frames further up the stack, so a logging helper can report what called it.
+// Skips add up when a logger is derived from another logger with a skip. Loggers that don't implement CallerSkipLogger
+// are returned as they are
+func WithCallerSkip(logger Logger, skip int) Logger {
+	if skipLogger, ok := logger.(CallerSkipLogger); ok && skip > 0 {
+		return skipLogger.WithCallerSkip(skip)
+	}
+	return logger
+}
+
+// Get the stack of whatever called this function's caller, however deep it is
+func callers() []uintptr {
+	pcs := make([]uintptr, maxFrameCount)
+	for {
+		// Skip runtime.Callers, this function, and its caller
+		depth := runtime.Callers(3, pcs)
+		if depth < len(pcs) {
+			return pcs[:depth]
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+}
+
+// Find the first frame outside of the skipped packages, then skip frames past it
+func findCaller(skip int) (*runtime.Frame, bool) {
+	frames := runtime.CallersFrames(callers())
+	found := false
+	for {
+		f, more := frames.Next()
+		if !found && !skipCallerPackage(getPackageName(f.Function)) {
+			found = true
+		}
+		if found {
+			if skip <= 0 {
+				return &f, true
+			}
+			skip--
+		}
+		if !more {
+			return nil, false
+		}
+	}
+}
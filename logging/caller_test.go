@@ -0,0 +1,161 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// A logging helper, which should report what called it
+func logFromHelper(logger logging.Logger, msg string) {
+	logging.WithCallerSkip(logger, 1).Info(msg)
+}
+
+func logFromDepth(logger logging.Logger, depth int) {
+	if depth > 0 {
+		logFromDepth(logger, depth-1)
+		return
+	}
+	logging.WithCallerSkip(logger, 41).WithField("deep", true).Info("Deep")
+}
+
+func currentFunction() string {
+	pc, _, _, _ := runtime.Caller(1)
+	return runtime.FuncForPC(pc).Name()
+}
+
+func TestCallerSkip(t *testing.T) {
+	tests := []struct {
+		name  string
+		inits logging.TLMLoggingInitialization
+	}{
+		{
+			name:  "Logrus",
+			inits: logging.TLMLoggingInitialization{Type: logging.LogrusLogType, Output: io.Discard},
+		},
+		{
+			name:  "Sink",
+			inits: logging.TLMLoggingInitialization{Type: logging.SinkLogType},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &test.inits
+			inits.Logging.Formatter.FunctionKey = "~"
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			function := currentFunction()
+			logFromHelper(tlm.Log(ctx), "Helper")
+			logFromHelper(tlm.Log(ctx).WithField("derived", true), "Derived")
+			logFromDepth(tlm.Log(ctx), 40)
+
+			logging.RegisterCallerSkipPackage("github.com/rcmaniac25/tlm/logging_test")
+			tlm.Log(ctx).Info("Skipped package")
+			logging.UnregisterCallerSkipPackage("github.com/rcmaniac25/tlm/logging_test")
+
+			util.AssertEqual(t, collector.GetNumberLogs(), 4, "count")
+			for i := 0; i < 3; i++ {
+				caller, ok := collector.GetCaller(i)
+				util.AssertEqualf(t, ok, true, "caller %d", i)
+				util.AssertEqualf(t, caller.Function, function, "caller %d", i)
+			}
+			caller, ok := collector.GetCaller(3)
+			util.AssertEqual(t, ok, true, "skipped package caller")
+			util.AssertEqual(t, caller.Function, "testing.tRunner", "skipped package caller")
+		})
+	}
+}
+
+func TestCallerSkipLogrusOutput(t *testing.T) {
+	output := new(bytes.Buffer)
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:      logging.LogrusLogType,
+		Output:    output,
+		Formatter: logging.Formatter{Type: logging.JsonFormat, FunctionKey: "~"},
+	}
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	logFromHelper(tlm.Log(ctx), "Helper")
+	var line map[string]any
+	util.AssertNoError(t, json.Unmarshal(output.Bytes(), &line), "json")
+	util.AssertEqual(t, line["function"], currentFunction(), "function")
+}
+
+func TestCallerKeys(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	tests := []struct {
+		name      string
+		logType   logging.LogType
+		formatter logging.Formatter
+		expected  []string
+		missing   []string
+	}{
+		{
+			name:      "Separate Line",
+			logType:   logging.SinkLogType,
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FunctionKey: "-", FileKey: "~", LineKey: "~"},
+			expected:  []string{"file=" + file + " line="},
+			missing:   []string{"func="},
+		},
+		{
+			name:      "Custom Keys",
+			logType:   logging.SinkLogType,
+			formatter: logging.Formatter{Type: logging.JsonFormat, FunctionKey: "fn", FileKey: "src", LineKey: "ln"},
+			expected:  []string{`"fn":"github.com/rcmaniac25/tlm/logging_test.TestCallerKeys`, `"src":"` + file + `"`, `"ln":`},
+		},
+		{
+			name:      "No File",
+			logType:   logging.SinkLogType,
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FunctionKey: "~", FileKey: "-"},
+			expected:  []string{"function=github.com/rcmaniac25/tlm/logging_test.TestCallerKeys"},
+			missing:   []string{"file="},
+		},
+		{
+			name:      "Logrus Json Separate Line",
+			logType:   logging.LogrusLogType,
+			formatter: logging.Formatter{Type: logging.JsonFormat, FileKey: "src", LineKey: "~"},
+			expected:  []string{`"src":"` + file + `"`, `"line":`, `"func":"github.com/rcmaniac25/tlm/logging_test.TestCallerKeys`},
+		},
+		{
+			name:      "Logrus Text No Line",
+			logType:   logging.LogrusLogType,
+			formatter: logging.Formatter{Type: logging.TextFormat, FunctionKey: "-", FileKey: "~", LineKey: "-"},
+			expected:  []string{"file=" + file + "\n"},
+			missing:   []string{"func=", "caller_test.go:"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := new(bytes.Buffer)
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{
+				Type:      test.logType,
+				Output:    output,
+				Formatter: test.formatter,
+			}
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			tlm.Log(ctx).Info("Caller")
+			for _, expected := range test.expected {
+				util.AssertContains(t, output.String(), expected, "output")
+			}
+			for _, missing := range test.missing {
+				util.AssertEqualf(t, strings.Contains(output.String(), missing), false, "%s in %s", missing, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+const (
+	consoleShortTimeFormat = "15:04:05.000"
+	consoleIndent          = "    "
+
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+)
+
+type ConsoleColorMode int
+
+const (
+	// Colors are used when writing to a terminal and the NO_COLOR environment variable isn't set
+	ConsoleColorAuto ConsoleColorMode = iota
+	ConsoleColorAlways
+	ConsoleColorNever
+)
+
+type ConsoleOptions struct {
+	Color ConsoleColorMode
+	// Show the time since the formatter was created instead of the time of day
+	RelativeTime bool
+}
+
+// A human friendly format for local development
+type consoleFormatter struct {
+	formatter  Formatter
+	colors     bool
+	showTime   bool
+	timeFormat string
+	relative   bool
+	start      time.Time
+}
+
+func newConsoleFormatter(formatter Formatter, output io.Writer, clock util.Clock) *consoleFormatter {
+	timeFormat := formatter.TimeFormat
+	if timeFormat == "" {
+		timeFormat = consoleShortTimeFormat
+	}
+	return &consoleFormatter{
+		formatter:  formatter,
+		colors:     useConsoleColors(formatter.Console.Color, output),
+		showTime:   formatter.TimeKey != "-",
+		timeFormat: timeFormat,
+		relative:   formatter.Console.RelativeTime,
+		start:      clock.Now(),
+	}
+}
+
+func useConsoleColors(mode ConsoleColorMode, output io.Writer) bool {
+	switch mode {
+	case ConsoleColorAlways:
+		return true
+	case ConsoleColorNever:
+		return false
+	}
+	// https://no-color.org
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(output)
+}
+
+func isTerminal(output io.Writer) bool {
+	file, ok := output.(*os.File)
+	return ok && isTerminalFile(file)
+}
+
+func consoleLevelColor(level LogLevel) string {
+	switch level {
+	case TraceLevel:
+		return "\x1b[90m"
+	case DebugLevel:
+		return "\x1b[37m"
+	case InfoLevel:
+		return "\x1b[36m"
+	case WarnLevel:
+		return "\x1b[33m"
+	case ErrorLevel:
+		return "\x1b[31m"
+	case PanicLevel, FatalLevel:
+		return "\x1b[1;31m"
+	}
+	return ""
+}
+
+// Write text, wrapped in the color if colors are enabled
+func (c *consoleFormatter) color(b *bytes.Buffer, color, text string) {
+	if c.colors && color != "" {
+		b.WriteString(color)
+		b.WriteString(text)
+		b.WriteString(ansiReset)
+		return
+	}
+	b.WriteString(text)
+}
+
+// Values that span multiple lines, and error causes, are printed below the log line instead of with the other fields
+func consoleMultiLineValue(key string, value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, strings.Contains(strings.TrimRight(v, "\n"), "\n")
+	case []any:
+		return "", key == ErrorCausesKey && len(v) > 0
+	}
+	return "", false
+}
+
+func (c *consoleFormatter) Format(entry *Entry) ([]byte, error) {
+	var b bytes.Buffer
+
+	if c.showTime {
+		if c.relative {
+			c.color(&b, ansiDim, fmt.Sprintf("%09.3f", entry.Time.Sub(c.start).Seconds()))
+		} else {
+			c.color(&b, ansiDim, entry.Time.Format(c.timeFormat))
+		}
+		b.WriteByte(' ')
+	}
+
+	level := strings.ToUpper(entry.Level.String())
+	if level == "" {
+		level = "?"
+	}
+	c.color(&b, consoleLevelColor(entry.Level), fmt.Sprintf("%-5s", level))
+	b.WriteByte(' ')
+
+	if entry.Caller != nil {
+		c.color(&b, ansiDim, fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line))
+		b.WriteByte(' ')
+	}
+
+	// Warnings and worse stand out
+	message := strings.TrimSuffix(entry.Message, "\n")
+	if entry.Level.Severity() >= WarnLevel.Severity() {
+		c.color(&b, ansiBold, message)
+	} else {
+		b.WriteString(message)
+	}
+
+	// The console is for people, so fields are never nested under a data key
+	multiLine := make([]field, 0)
+	for _, fieldItem := range c.formatter.layoutFields(entry) {
+		if _, ok := consoleMultiLineValue(fieldItem.key, fieldItem.value); ok {
+			multiLine = append(multiLine, fieldItem)
+			continue
+		}
+
+		var field bytes.Buffer
+		writeLogfmtKey(&field, fieldItem.key)
+		field.WriteByte('=')
+		writeLogfmtValue(&field, fieldItem.value, time.RFC3339)
+		b.WriteByte(' ')
+		c.color(&b, ansiDim, field.String())
+	}
+	b.WriteByte('\n')
+
+	for _, fieldItem := range multiLine {
+		c.color(&b, ansiDim, consoleIndent+fieldItem.key+":")
+		b.WriteByte('\n')
+		if text, _ := consoleMultiLineValue(fieldItem.key, fieldItem.value); text != "" {
+			for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+				b.WriteString(consoleIndent + consoleIndent + line + "\n")
+			}
+			continue
+		}
+		c.writeCauses(&b, fieldItem.value.([]any), consoleIndent+consoleIndent)
+	}
+	return b.Bytes(), nil
+}
+
+// Error causes are printed as a tree
+func (c *consoleFormatter) writeCauses(b *bytes.Buffer, causes []any, indent string) {
+	for _, cause := range causes {
+		fields, ok := cause.(util.Fields)
+		if !ok {
+			b.WriteString(fmt.Sprintf("%s- %v\n", indent, cause))
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf("%s- %v", indent, fields[CauseMessageKey]))
+		if errType, ok := fields[CauseTypeKey]; ok {
+			b.WriteByte(' ')
+			c.color(b, ansiDim, fmt.Sprintf("(%v)", errType))
+		}
+		b.WriteByte('\n')
+		if subCauses, ok := fields[ErrorCausesKey].([]any); ok {
+			c.writeCauses(b, subCauses, indent+"  ")
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package logging_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+func TestConsoleFormatTerminal(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	terminal, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	defer terminal.Close()
+
+	formatter, err := logging.Formatter{Type: logging.ConsoleFormat}.NewEntryFormatter(terminal, nil)
+	util.AssertNoError(t, err, "formatter")
+	data, err := formatter.Format(&logging.Entry{Level: logging.InfoLevel, Message: "Terminal"})
+	util.AssertNoError(t, err, "format")
+	util.AssertEqual(t, strings.Contains(string(data), "\x1b["), true, "colors")
+}
@@ -0,0 +1,182 @@
+package logging_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+func TestConsoleFormat(t *testing.T) {
+	entryTime := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	tests := []struct {
+		name      string
+		formatter logging.Formatter
+		entry     logging.Entry
+		expected  string
+	}{
+		{
+			name:      "Basic",
+			formatter: logging.Formatter{Type: logging.ConsoleFormat},
+			entry:     logging.Entry{Time: entryTime, Level: logging.InfoLevel, Message: "Hello"},
+			expected:  "03:04:05.006 INFO  Hello\n",
+		},
+		{
+			name:      "Fields",
+			formatter: logging.Formatter{Type: logging.ConsoleFormat},
+			entry: logging.Entry{
+				Time:    entryTime,
+				Level:   logging.WarnLevel,
+				Message: "Fields",
+				Fields:  util.Fields{"b": "two words", "a": 1},
+			},
+			expected: "03:04:05.006 WARN  Fields a=1 b=\"two words\"\n",
+		},
+		{
+			name:      "Caller",
+			formatter: logging.Formatter{Type: logging.ConsoleFormat, TimeKey: "-"},
+			entry: logging.Entry{
+				Time:    entryTime,
+				Level:   logging.TraceLevel,
+				Message: "Caller",
+				Caller:  &runtime.Frame{Function: "main.main", File: "/src/main.go", Line: 12},
+			},
+			expected: "TRACE main.go:12 Caller\n",
+		},
+		{
+			name:      "Time Format",
+			formatter: logging.Formatter{Type: logging.ConsoleFormat, TimeFormat: time.RFC3339},
+			entry:     logging.Entry{Time: entryTime, Level: logging.ErrorLevel, Message: "Time"},
+			expected:  "2020-01-02T03:04:05Z ERROR Time\n",
+		},
+		{
+			name:      "Multi-line",
+			formatter: logging.Formatter{Type: logging.ConsoleFormat},
+			entry: logging.Entry{
+				Time:    entryTime,
+				Level:   logging.ErrorLevel,
+				Message: "Failed",
+				Fields: util.Fields{
+					"error":               "outer: inner",
+					logging.ErrorStackKey: "main.main()\n\t/src/main.go:12\n",
+					logging.ErrorCausesKey: []any{
+						util.Fields{logging.CauseMessageKey: "inner", logging.CauseTypeKey: "*errors.errorString"},
+					},
+				},
+			},
+			expected: "03:04:05.006 ERROR Failed error=\"outer: inner\"\n" +
+				"    causes:\n" +
+				"        - inner (*errors.errorString)\n" +
+				"    stack:\n" +
+				"        main.main()\n" +
+				"        \t/src/main.go:12\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.formatter.Format(&test.entry)
+			util.AssertNoError(t, err, "format")
+			util.AssertEqual(t, string(data), test.expected, "output")
+		})
+	}
+}
+
+func TestConsoleFormatColors(t *testing.T) {
+	formatter := logging.Formatter{Type: logging.ConsoleFormat, Console: logging.ConsoleOptions{Color: logging.ConsoleColorAlways}}
+	data, err := formatter.Format(&logging.Entry{Level: logging.WarnLevel, Message: "Colors", Fields: util.Fields{"a": 1}})
+	util.AssertNoError(t, err, "format")
+
+	output := string(data)
+	util.AssertContains(t, output, "\x1b[33mWARN \x1b[0m", "level color")
+	util.AssertContains(t, output, "\x1b[1mColors\x1b[0m", "bold message")
+	util.AssertContains(t, output, "\x1b[2ma=1\x1b[0m", "dim field")
+}
+
+func TestConsoleFormatRelativeTime(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Formatter = logging.Formatter{Type: logging.ConsoleFormat, Console: logging.ConsoleOptions{RelativeTime: true}}
+	logger, buffer := createLogger(logArgs)
+
+	logger.Info("Relative")
+	util.AssertEqual(t, strings.HasPrefix(buffer.String(), "00000.0"), true, fmt.Sprintf("relative time: %s", buffer.String()))
+}
+
+func TestConsoleFormatColorDetection(t *testing.T) {
+	// Character devices that aren't terminals don't get colors
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	util.AssertNoError(t, err, "open")
+	defer devNull.Close()
+
+	tests := []struct {
+		name    string
+		mode    logging.ConsoleColorMode
+		noColor string
+		colors  bool
+	}{
+		{
+			name:   "Auto",
+			mode:   logging.ConsoleColorAuto,
+			colors: false,
+		},
+		{
+			name:    "Auto NO_COLOR",
+			mode:    logging.ConsoleColorAuto,
+			noColor: "1",
+			colors:  false,
+		},
+		{
+			name:    "Always NO_COLOR",
+			mode:    logging.ConsoleColorAlways,
+			noColor: "1",
+			colors:  true,
+		},
+		{
+			name:   "Never",
+			mode:   logging.ConsoleColorNever,
+			colors: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", test.noColor)
+
+			logArgs := new(logging.TLMLoggingInitialization)
+			logArgs.Formatter = logging.Formatter{Type: logging.ConsoleFormat, Console: logging.ConsoleOptions{Color: test.mode}}
+			logArgs.Output = devNull
+			logger, err := logging.InitLogrus(logArgs)
+			util.AssertNoError(t, err, "init")
+
+			lrus := logger.(*logging.LogrusImpl).Logger
+			data, err := lrus.Formatter.Format(lrus.WithField("a", 1))
+			util.AssertNoError(t, err, "format")
+			util.AssertEqual(t, strings.Contains(string(data), "\x1b["), test.colors, "colors")
+		})
+	}
+}
+
+func TestConsoleFormatRelativeTimeBuiltOnce(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := util.ClockFunc(func() time.Time { return start })
+	formatter, err := logging.Formatter{Type: logging.ConsoleFormat, Console: logging.ConsoleOptions{RelativeTime: true}}.NewEntryFormatter(nil, clock)
+	util.AssertNoError(t, err, "formatter")
+
+	data, err := formatter.Format(&logging.Entry{Level: logging.InfoLevel, Time: start.Add(1500 * time.Millisecond), Message: "Later"})
+	util.AssertNoError(t, err, "format")
+	util.AssertEqual(t, strings.HasPrefix(string(data), "00001.500"), true, fmt.Sprintf("relative time: %s", data))
+}
+
+func TestConsoleFormatNotTerminal(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Formatter = logging.Formatter{Type: logging.ConsoleFormat}
+	logger, buffer := createLogger(logArgs)
+
+	logger.WithError(fmt.Errorf("outer: %w", errors.New("inner"))).Error("No colors")
+	util.AssertEqual(t, strings.Contains(buffer.String(), "\x1b["), false, "colors")
+	util.AssertContains(t, buffer.String(), "ERROR No colors error=\"outer: inner\" error_type=*fmt.wrapError\n    causes:\n        - inner (*errors.errorString)\n", "output")
+}
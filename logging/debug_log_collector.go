@@ -1,155 +1,367 @@
 package logging
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/rcmaniac25/tlm/util"
 )
 
+// Deprecated: entries are no longer serialized, so these keys aren't used
 const (
 	LogMessageKey = "lc_log_message"
 	LogLevelKey   = "lc_log_level"
 	LogTimeKey    = "lc_log_time"
 )
 
+// Reserved field used by DebugLogCollector.Scope. Only the collector sees it, and it's removed from collected entries
+const CollectorScopeKey = "tlm_collector_scope"
+
+// Collects log entries in memory for tests. It's a Sink, so it captures entries the same way from any logger type.
+// Safe for concurrent use
 type DebugLogCollector struct {
-	buffer bytes.Buffer
+	store *collectorStore
+	// Empty for the root collector, otherwise only entries logged within the scope are seen
+	scope string
+}
+
+// Entries shared by a collector and all of its scopes
+type collectorStore struct {
+	lock sync.Mutex
+	logs []collectedLog
+	// Exit codes and panic values that arrived before their log
+	pendingExitcodes   []pendingTermination
+	pendingPanicValues []pendingTermination
+	// Closed and replaced whenever a log is collected
+	changed   chan struct{}
+	nextScope int
+}
+
+type collectedLog struct {
+	entry    Entry
+	scope    string
+	exitcode int
+	exited   bool
+
+	panicValue any
+	panicked   bool
+
+	// Set for fatal and panic logs, to match them with their exit code or panic value
+	goroutine uint64
+}
+
+// An exit code or panic value, and the goroutine that logged what caused it
+type pendingTermination struct {
+	goroutine uint64
+	value     any
+}
+
+// The ID of the current goroutine. ExitFunc and PanicFunc are called on the goroutine that wrote the fatal or panic log,
+// so exit codes and panic values are matched with logs from the same goroutine. Otherwise, parallel tests could get
+// each other's exit codes
+func goroutineID() uint64 {
+	var buf [64]byte
+	// Starts with "goroutine 123 [running]:"
+	stack := strings.TrimPrefix(string(buf[:runtime.Stack(buf[:], false)]), "goroutine ")
+	if end := strings.IndexByte(stack, ' '); end >= 0 {
+		stack = stack[:end]
+	}
+	id, _ := strconv.ParseUint(stack, 10, 64)
+	return id
+}
 
-	logs      []map[string]any
-	exitcodes []int
+// Take the first pending value from the goroutine
+func takePending(pending []pendingTermination, goroutine uint64) (any, []pendingTermination, bool) {
+	for i, termination := range pending {
+		if termination.goroutine == goroutine {
+			return termination.value, append(pending[:i:i], pending[i+1:]...), true
+		}
+	}
+	return nil, pending, false
 }
 
 func NewDebugLogCollector() *DebugLogCollector {
-	return new(DebugLogCollector)
+	return &DebugLogCollector{
+		store: &collectorStore{
+			changed: make(chan struct{}),
+		},
+	}
 }
 
+// Add the collector to the sinks. If no logger type is set, SinkLogType is used so only the collector gets the logs. For
+// other logger types, output is discarded if no Output is set. If no ExitFunc is set, the collector records exit codes
+// instead of exiting. Panics are only recorded if PanicFunc is set to OnPanic, since tests often expect the panic
 func (c *DebugLogCollector) SetupInitialization(init *TLMLoggingInitialization) {
-	init.Type = LogrusLogType
-	init.Output = &c.buffer
-	init.Formatter.Type = JsonFormat
-	init.Formatter.MessageKey = LogMessageKey
-	init.Formatter.LevelKey = LogLevelKey
-	init.Formatter.TimeKey = LogTimeKey
-	init.Formatter.TimeFormat = time.RFC3339Nano
+	if init.Type == CustomLogType && init.CustomeType == "" {
+		init.Type = SinkLogType
+	}
+	// SinkLogType writes to Output alongside the sinks, so it's left unset
+	if init.Output == nil && init.Type != SinkLogType {
+		init.Output = io.Discard
+	}
+	if init.ExitFunc == nil {
+		init.ExitFunc = c.OnExitCode
+	}
+	init.Sinks = append(init.Sinks, c)
 }
 
-func (c *DebugLogCollector) OnExitCode(exitcode int) {
-	c.exitcodes = append(c.exitcodes, exitcode)
+// Get a logger and a collector that only sees what's logged by that logger and the loggers derived from it. This lets
+// parallel tests share a logger without seeing each other's logs. If the logger is a TLMLogger, so is the returned logger
+func (c *DebugLogCollector) Scope(logger Logger) (Logger, *DebugLogCollector) {
+	c.store.lock.Lock()
+	c.store.nextScope++
+	scope := strconv.Itoa(c.store.nextScope)
+	c.store.lock.Unlock()
+
+	if c.scope != "" {
+		scope = c.scope + "/" + scope
+	}
+	return logger.WithField(CollectorScopeKey, scope), &DebugLogCollector{store: c.store, scope: scope}
 }
 
-func (c *DebugLogCollector) populateLogs() error {
-	decoder := json.NewDecoder(&c.buffer)
-	for {
-		var fields map[string]any
-		err := decoder.Decode(&fields)
-		if err == nil {
-			c.logs = append(c.logs, fields)
+// Copy fields without the collector scope
+func withoutCollectorScope(fields util.Fields) util.Fields {
+	copied := make(util.Fields, len(fields))
+	for key, value := range fields {
+		if key != CollectorScopeKey {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+func (c *DebugLogCollector) inScope(log *collectedLog) bool {
+	return c.scope == "" || log.scope == c.scope || strings.HasPrefix(log.scope, c.scope+"/")
+}
+
+// Get the logs in the collector's scope. The caller must hold the lock
+func (c *DebugLogCollector) scopedLogs() []*collectedLog {
+	logs := make([]*collectedLog, 0, len(c.store.logs))
+	for i := range c.store.logs {
+		if c.inScope(&c.store.logs[i]) {
+			logs = append(logs, &c.store.logs[i])
+		}
+	}
+	return logs
+}
+
+// Get a copy of the logs in the collector's scope
+func (c *DebugLogCollector) snapshot() []collectedLog {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	scoped := c.scopedLogs()
+	logs := make([]collectedLog, len(scoped))
+	for i, log := range scoped {
+		logs[i] = *log
+	}
+	return logs
+}
+
+func (c *DebugLogCollector) Write(entry *Entry) error {
+	log := collectedLog{entry: *entry, scope: entry.collectorScope}
+	// Custom loggers that don't use TLM's sinks keep the scope as a field
+	if scope, ok := entry.Fields[CollectorScopeKey]; ok {
+		log.scope, _ = scope.(string)
+		log.entry.Fields = withoutCollectorScope(entry.Fields)
+	}
+
+	if entry.Level == FatalLevel || entry.Level == PanicLevel {
+		log.goroutine = goroutineID()
+	}
+
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	if entry.Level == FatalLevel {
+		if exitcode, pending, ok := takePending(c.store.pendingExitcodes, log.goroutine); ok {
+			log.exitcode, log.exited = exitcode.(int), true
+			c.store.pendingExitcodes = pending
 		}
-		if err != nil && err == io.EOF {
-			// Reached the end
-			return nil
+	}
+	if entry.Level == PanicLevel {
+		if value, pending, ok := takePending(c.store.pendingPanicValues, log.goroutine); ok {
+			log.panicValue, log.panicked = value, true
+			c.store.pendingPanicValues = pending
 		}
-		if err != nil {
-			return err
+	}
+	c.store.logs = append(c.store.logs, log)
+	close(c.store.changed)
+	c.store.changed = make(chan struct{})
+	return nil
+}
+
+func (c *DebugLogCollector) Close() error {
+	return nil
+}
+
+// Record an exit code. It's matched with the first fatal log from the same goroutine that doesn't have one yet
+func (c *DebugLogCollector) OnExitCode(exitcode int) {
+	goroutine := goroutineID()
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	for _, log := range c.scopedLogs() {
+		if log.entry.Level == FatalLevel && !log.exited && log.goroutine == goroutine {
+			log.exitcode = exitcode
+			log.exited = true
+			return
 		}
 	}
+	c.store.pendingExitcodes = append(c.store.pendingExitcodes, pendingTermination{goroutine: goroutine, value: exitcode})
 }
 
+// Record the value a panic log panicked with. It's matched with the first panic log from the same goroutine that
+// doesn't have one yet
+func (c *DebugLogCollector) OnPanic(value any) {
+	goroutine := goroutineID()
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	for _, log := range c.scopedLogs() {
+		if log.entry.Level == PanicLevel && !log.panicked && log.goroutine == goroutine {
+			log.panicValue = value
+			log.panicked = true
+			return
+		}
+	}
+	c.store.pendingPanicValues = append(c.store.pendingPanicValues, pendingTermination{goroutine: goroutine, value: value})
+}
+
+// Remove the logs in the collector's scope
 func (c *DebugLogCollector) Clear() {
-	c.populateLogs()
-	c.logs = make([]map[string]any, 0)
-	c.exitcodes = make([]int, 0)
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	logs := make([]collectedLog, 0)
+	if c.scope != "" {
+		for _, log := range c.store.logs {
+			if !c.inScope(&log) {
+				logs = append(logs, log)
+			}
+		}
+	}
+	c.store.logs = logs
+	if c.scope == "" {
+		c.store.pendingExitcodes = make([]pendingTermination, 0)
+		c.store.pendingPanicValues = make([]pendingTermination, 0)
+	}
 }
 
 func (c *DebugLogCollector) GetNumberLogs() int {
-	if err := c.populateLogs(); err != nil {
-		return -1
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	return len(c.scopedLogs())
+}
+
+// Wait until there are at least count logs in the collector's scope. Returns false if the timeout is reached first
+func (c *DebugLogCollector) WaitForLogs(count int, timeout time.Duration) bool {
+	_, ok := c.waitFor(timeout, func(logs []*collectedLog) (Entry, bool) {
+		return Entry{}, len(logs) >= count
+	})
+	return ok
+}
+
+// Wait until a log matches the query. Returns false if the timeout is reached first
+func (c *DebugLogCollector) WaitForLog(query LogQuery, timeout time.Duration) (Entry, bool) {
+	return c.waitFor(timeout, func(logs []*collectedLog) (Entry, bool) {
+		for _, log := range logs {
+			if query.Matches(&log.entry) {
+				return log.entry, true
+			}
+		}
+		return Entry{}, false
+	})
+}
+
+func (c *DebugLogCollector) waitFor(timeout time.Duration, done func(logs []*collectedLog) (Entry, bool)) (Entry, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		c.store.lock.Lock()
+		entry, ok := done(c.scopedLogs())
+		changed := c.store.changed
+		c.store.lock.Unlock()
+		if ok {
+			return entry, true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return Entry{}, false
+		}
 	}
-	return len(c.logs)
 }
 
-func (c *DebugLogCollector) getLogField(logIndex int, field string) (any, error) {
+func (c *DebugLogCollector) getLog(logIndex int) (collectedLog, error) {
 	if logIndex < 0 {
-		return nil, errors.New("<dev> log index must be >= 0")
+		return collectedLog{}, errors.New("<dev> log index must be >= 0")
 	}
 
-	if logIndex >= len(c.logs) {
-		if err := c.populateLogs(); err != nil {
-			return nil, err
-		}
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	logs := c.scopedLogs()
+	if logIndex >= len(logs) {
+		return collectedLog{}, errors.New("<dev> log index exceeds number of logs received")
 	}
+	return *logs[logIndex], nil
+}
 
-	if logIndex >= len(c.logs) {
-		return nil, errors.New("<dev> log index exceeds number of logs received")
+// Get the whole entry that was logged
+func (c *DebugLogCollector) GetEntry(logIndex int) (Entry, bool) {
+	log, err := c.getLog(logIndex)
+	if err != nil {
+		return Entry{}, false
 	}
-
-	return c.logs[logIndex][field], nil
+	return log.entry, true
 }
 
 func (c *DebugLogCollector) GetMessage(logIndex int) string {
-	field, err := c.getLogField(logIndex, LogMessageKey)
+	log, err := c.getLog(logIndex)
 	if err != nil {
 		return err.Error()
 	}
-	if msg, ok := field.(string); ok {
-		return msg
-	}
-	return "Unknown type of log message. Expected string"
+	return log.entry.Message
 }
 
 func (c *DebugLogCollector) GetLogLevel(logIndex int) LogLevel {
-	field, err := c.getLogField(logIndex, LogLevelKey)
+	log, err := c.getLog(logIndex)
 	if err != nil {
 		return -1
 	}
-	level, ok := field.(string)
-	if !ok {
-		return -2
-	}
-	switch level {
-	case "debug":
-		return DebugLevel
-	case "info":
-		return InfoLevel
-	case "warning":
-		return WarnLevel
-	case "error":
-		return ErrorLevel
-	// The next 2 levels could potentially get lost due to the log level itself
-	case "panic":
-		return PanicLevel
-	case "fatal":
-		return FatalLevel
-	default:
-		// Not actually default, but gives a good way to go "no idea"
-		return DefaultLevel
-	}
+	return log.entry.Level
 }
 
 func (c *DebugLogCollector) GetTime(logIndex int) time.Time {
-	field, err := c.getLogField(logIndex, LogTimeKey)
+	log, err := c.getLog(logIndex)
 	if err != nil {
 		return time.Time{}
 	}
-	if timeStr, ok := field.(string); ok {
-		if t, err := time.Parse(time.RFC3339Nano, timeStr); err == nil {
-			return t
-		}
+	return log.entry.Time
+}
+
+// Only set when the caller is reported, see Formatter.FunctionKey
+func (c *DebugLogCollector) GetCaller(logIndex int) (*runtime.Frame, bool) {
+	log, err := c.getLog(logIndex)
+	if err != nil || log.entry.Caller == nil {
+		return nil, false
 	}
-	return time.Time{}
+	return log.entry.Caller, true
 }
 
+// Fields keep the type they were logged with
 func (c *DebugLogCollector) GetField(logIndex int, field string) (any, bool) {
-	switch field {
-	case LogMessageKey, LogLevelKey, LogTimeKey:
+	log, err := c.getLog(logIndex)
+	if err != nil {
 		return nil, false
 	}
-	if f, err := c.getLogField(logIndex, field); err == nil {
-		return f, true
-	}
-	return nil, false
+	value, ok := log.entry.Fields[field]
+	return value, ok
 }
 
 func (c *DebugLogCollector) GetFieldFunc(logIndex int, field string) func() (any, bool) {
@@ -159,58 +371,29 @@ func (c *DebugLogCollector) GetFieldFunc(logIndex int, field string) func() (any
 }
 
 func (c *DebugLogCollector) GetFatalExitcode(logIndex int) (int, bool) {
-	if logIndex < 0 {
+	log, err := c.getLog(logIndex)
+	if err != nil {
 		return -1, false
 	}
+	return log.exitcode, log.exited
+}
 
-	if logIndex >= len(c.logs) {
-		if err := c.populateLogs(); err != nil {
-			return -2, false
-		}
-	}
-
-	if logIndex >= len(c.logs) {
-		return -3, false
-	}
-
-	// This attempts to sync fatal function calls to error codes
-	type exitcodesync struct {
-		valid bool
-		value int
+func (c *DebugLogCollector) GetFatalExitcodeFunc(logIndex int) func() (any, bool) {
+	return func() (any, bool) {
+		return c.GetFatalExitcode(logIndex)
 	}
-	errcodeIndex := 0
-	var codes []exitcodesync
-	for i, log := range c.logs {
-		if i > logIndex {
-			// No need to keep going if we already reached the index
-			break
-		}
-		if errcodeIndex >= len(c.exitcodes) {
-			// No need to keep going if there are no more codes to populate
-			break
-		}
-		if log[LogLevelKey] != "fatal" {
-			codes = append(codes, exitcodesync{
-				valid: false,
-				value: 0,
-			})
-			continue
-		}
+}
 
-		codes = append(codes, exitcodesync{
-			valid: true,
-			value: c.exitcodes[errcodeIndex],
-		})
-		errcodeIndex++
-	}
-	if logIndex >= len(codes) {
-		return -4, false
+func (c *DebugLogCollector) GetPanicValue(logIndex int) (any, bool) {
+	log, err := c.getLog(logIndex)
+	if err != nil {
+		return nil, false
 	}
-	return codes[logIndex].value, codes[logIndex].valid
+	return log.panicValue, log.panicked
 }
 
-func (c *DebugLogCollector) GetFatalExitcodeFunc(logIndex int) func() (any, bool) {
+func (c *DebugLogCollector) GetPanicValueFunc(logIndex int) func() (any, bool) {
 	return func() (any, bool) {
-		return c.GetFatalExitcode(logIndex)
+		return c.GetPanicValue(logIndex)
 	}
 }
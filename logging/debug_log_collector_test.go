@@ -0,0 +1,372 @@
+package logging_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+func TestDebugLogCollectorCustomLogger(t *testing.T) {
+	util.AssertNoError(t, logging.RegisterLogger("CollectedLogger", func(_ *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		return &logging.NullLogger, nil
+	}), "register")
+	defer logging.UnregisterLogger("CollectedLogger")
+
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:        logging.CustomLogType,
+		CustomeType: "CollectedLogger",
+		Formatter:   logging.Formatter{FunctionKey: "~"},
+	}
+	collector := logging.NewDebugLogCollector()
+	collector.SetupInitialization(inits.Logging)
+	util.AssertEqual(t, inits.Logging.Type, logging.CustomLogType, "type kept")
+
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	logTime := time.Now()
+	tlm.Log(ctx).WithField("count", 3).Warn("Custom")
+
+	util.AssertEqual(t, collector.GetNumberLogs(), 1, "count")
+	util.AssertEqual(t, collector.GetMessage(0), "Custom", "message")
+	util.AssertEqual(t, collector.GetLogLevel(0), logging.WarnLevel, "level")
+	util.AssertEqualExistsFunc(t, collector.GetFieldFunc(0, "count"), 3, "field")
+	util.AssertEqual(t, collector.GetTime(0).Sub(logTime) < time.Second, true, "time")
+
+	caller, ok := collector.GetCaller(0)
+	util.AssertEqual(t, ok, true, "caller")
+	util.AssertEqual(t, caller.Function, "github.com/rcmaniac25/tlm/logging_test.TestDebugLogCollectorCustomLogger", "caller function")
+
+	entry, ok := collector.GetEntry(0)
+	util.AssertEqual(t, ok, true, "entry")
+	util.AssertEqual(t, entry.Message, "Custom", "entry message")
+	_, ok = collector.GetEntry(1)
+	util.AssertEqual(t, ok, false, "no entry")
+}
+
+func TestDebugLogCollectorOutput(t *testing.T) {
+	output := new(bytes.Buffer)
+	tests := []struct {
+		name     string
+		inits    logging.TLMLoggingInitialization
+		expected io.Writer
+	}{
+		{
+			name:     "Logrus",
+			inits:    logging.TLMLoggingInitialization{Type: logging.LogrusLogType},
+			expected: io.Discard,
+		},
+		{
+			name:     "Logrus Output",
+			inits:    logging.TLMLoggingInitialization{Type: logging.LogrusLogType, Output: output},
+			expected: output,
+		},
+		{
+			name:     "Default Sink",
+			inits:    logging.TLMLoggingInitialization{},
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logging.NewDebugLogCollector().SetupInitialization(&test.inits)
+			util.AssertEqual(t, test.inits.Output, test.expected, "output")
+		})
+	}
+}
+
+func TestDebugLogCollectorExitcodes(t *testing.T) {
+	collector := logging.NewDebugLogCollector()
+
+	// Exit codes are matched to fatal logs in order, even if the exit comes first
+	collector.OnExitCode(2)
+	util.AssertNoError(t, collector.Write(&logging.Entry{Level: logging.FatalLevel, Message: "first"}), "write")
+	util.AssertNoError(t, collector.Write(&logging.Entry{Level: logging.ErrorLevel, Message: "error"}), "write")
+	util.AssertNoError(t, collector.Write(&logging.Entry{Level: logging.FatalLevel, Message: "second"}), "write")
+	collector.OnExitCode(3)
+
+	util.AssertEqualExistsFunc(t, collector.GetFatalExitcodeFunc(0), 2, "first")
+	_, ok := collector.GetFatalExitcode(1)
+	util.AssertEqual(t, ok, false, "not fatal")
+	util.AssertEqualExistsFunc(t, collector.GetFatalExitcodeFunc(2), 3, "second")
+
+	collector.Clear()
+	util.AssertEqual(t, collector.GetNumberLogs(), 0, "cleared")
+}
+
+// Parallel tests share the collector, so exit codes and panic values go to the log from the same goroutine
+func TestDebugLogCollectorParallelTerminations(t *testing.T) {
+	collector := logging.NewDebugLogCollector()
+	run := func(f func()) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+		<-done
+	}
+	first := make(chan struct{})
+	second := make(chan struct{})
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		collector.Write(&logging.Entry{Level: logging.FatalLevel, Message: "first fatal"})
+		collector.Write(&logging.Entry{Level: logging.PanicLevel, Message: "first panic"})
+		close(first)
+		<-second
+		collector.OnExitCode(3)
+		collector.OnPanic("first")
+	}()
+
+	<-first
+	run(func() {
+		collector.Write(&logging.Entry{Level: logging.FatalLevel, Message: "second fatal"})
+		collector.OnExitCode(2)
+		collector.Write(&logging.Entry{Level: logging.PanicLevel, Message: "second panic"})
+		collector.OnPanic("second")
+	})
+	close(second)
+	<-firstDone
+
+	util.AssertEqualExistsFunc(t, collector.GetFatalExitcodeFunc(0), 3, "first fatal")
+	util.AssertEqualExistsFunc(t, collector.GetPanicValueFunc(1), "first", "first panic")
+	util.AssertEqualExistsFunc(t, collector.GetFatalExitcodeFunc(2), 2, "second fatal")
+	util.AssertEqualExistsFunc(t, collector.GetPanicValueFunc(3), "second", "second panic")
+}
+
+// Records failures instead of failing the test
+type fakeTestingT struct {
+	failure string
+}
+
+func (f *fakeTestingT) Helper() {}
+
+func (f *fakeTestingT) Fatalf(format string, args ...any) {
+	f.failure = fmt.Sprintf(format, args...)
+}
+
+func createQueryCollector(t *testing.T) (*logging.DebugLogCollector, time.Time) {
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{Level: logging.DebugLevel}
+	collector := logging.NewDebugLogCollector()
+	collector.SetupInitialization(inits.Logging)
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	start := time.Now()
+	logger := tlm.Log(ctx)
+	logger.WithField(logging.LoggerNameKey, "db").Debug("Connecting")
+	logger.WithFields(util.Fields{logging.LoggerNameKey: "db", "attempt": 1}).Warn("Connection timed out")
+	logger.WithError(errors.New("refused")).WithField("attempt", 2).Error("Connection failed")
+	logger.WithField("user", "bob").Info("Request served")
+	return collector, start
+}
+
+func TestDebugLogCollectorFind(t *testing.T) {
+	collector, start := createQueryCollector(t)
+
+	tests := []struct {
+		name     string
+		query    logging.LogQuery
+		expected []string
+	}{
+		{
+			name:     "Any",
+			query:    logging.LogQuery{},
+			expected: []string{"Connecting", "Connection timed out", "Connection failed", "Request served"},
+		},
+		{
+			name:     "Level",
+			query:    logging.LogQuery{Level: logging.InfoLevel},
+			expected: []string{"Request served"},
+		},
+		{
+			name:     "Min Level",
+			query:    logging.LogQuery{MinLevel: logging.WarnLevel},
+			expected: []string{"Connection timed out", "Connection failed"},
+		},
+		{
+			name:     "Message",
+			query:    logging.LogQuery{Message: "Connecting"},
+			expected: []string{"Connecting"},
+		},
+		{
+			name:     "Message Regex",
+			query:    logging.LogQuery{MessageRegex: regexp.MustCompile("^Connection (timed|failed)")},
+			expected: []string{"Connection timed out", "Connection failed"},
+		},
+		{
+			name:     "Fields",
+			query:    logging.LogQuery{Fields: util.Fields{"attempt": 2, "error": "refused"}},
+			expected: []string{"Connection failed"},
+		},
+		{
+			name:     "Field Type",
+			query:    logging.LogQuery{Fields: util.Fields{"attempt": int64(1)}},
+			expected: []string{},
+		},
+		{
+			name:     "Has Fields",
+			query:    logging.LogQuery{HasFields: []string{"attempt"}},
+			expected: []string{"Connection timed out", "Connection failed"},
+		},
+		{
+			name:     "Logger Name",
+			query:    logging.LogQuery{LoggerName: "db"},
+			expected: []string{"Connecting", "Connection timed out"},
+		},
+		{
+			name:     "Time Range",
+			query:    logging.LogQuery{Since: start.Add(-time.Minute), Until: time.Now()},
+			expected: []string{"Connecting", "Connection timed out", "Connection failed", "Request served"},
+		},
+		{
+			name:     "Future",
+			query:    logging.LogQuery{Since: time.Now().Add(time.Minute)},
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := collector.Find(test.query)
+			messages := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				messages = append(messages, entry.Message)
+			}
+			util.AssertEqual(t, fmt.Sprint(messages), fmt.Sprint(test.expected), "messages")
+		})
+	}
+}
+
+func TestDebugLogCollectorRequire(t *testing.T) {
+	collector, _ := createQueryCollector(t)
+
+	entry := collector.RequireLogged(t, logging.LogQuery{Level: logging.WarnLevel, LoggerName: "db"})
+	util.AssertEqual(t, entry.Fields["attempt"], 1, "entry field")
+	collector.RequireNotLogged(t, logging.LogQuery{Level: logging.FatalLevel})
+	entries := collector.RequireSequence(t,
+		logging.LogQuery{Message: "Connecting"},
+		logging.LogQuery{Level: logging.ErrorLevel},
+		logging.LogQuery{HasFields: []string{"user"}},
+	)
+	util.AssertEqual(t, len(entries), 3, "sequence entries")
+
+	fake := new(fakeTestingT)
+	collector.RequireLogged(fake, logging.LogQuery{Level: logging.WarnLevel, Fields: util.Fields{"attempt": 3}})
+	util.AssertContains(t, fake.failure, "Expected a log matching: level=warn attempt=3", "query")
+	util.AssertContains(t, fake.failure, "0: DEBUG Connecting logger=db\n       - level is debug, expected warn", "mismatched level")
+	util.AssertContains(t, fake.failure, "1: WARN  Connection timed out attempt=1 logger=db\n       - field attempt is 1 (int), expected 3 (int)", "mismatched field")
+
+	fake = new(fakeTestingT)
+	collector.RequireNotLogged(fake, logging.LogQuery{MinLevel: logging.ErrorLevel})
+	util.AssertContains(t, fake.failure, "Expected no log matching: level>=error, but log 2 matched", "not logged")
+
+	fake = new(fakeTestingT)
+	collector.RequireSequence(fake, logging.LogQuery{Level: logging.ErrorLevel}, logging.LogQuery{Message: "Connecting"})
+	util.AssertContains(t, fake.failure, "Expected log 1 of the sequence after the previous match: msg=\"Connecting\"", "sequence")
+	util.AssertContains(t, fake.failure, "0: DEBUG Connecting logger=db\n       + matches", "out of order match")
+
+	collector.Clear()
+	fake = new(fakeTestingT)
+	collector.RequireLogged(fake, logging.LogQuery{})
+	util.AssertContains(t, fake.failure, "no logs were collected", "empty")
+}
+
+func TestDebugLogCollectorScope(t *testing.T) {
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = new(logging.TLMLoggingInitialization)
+	collector := logging.NewDebugLogCollector()
+	collector.SetupInitialization(inits.Logging)
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+	logger := tlm.Log(ctx)
+
+	t.Run("Parallel", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			i := i
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				scopedLogger, scoped := collector.Scope(logger)
+				for n := 0; n < 20; n++ {
+					go scopedLogger.WithField("n", n).Info("Scoped")
+				}
+				util.AssertEqual(t, scoped.WaitForLogs(20, 5*time.Second), true, "wait")
+				util.AssertEqual(t, scoped.GetNumberLogs(), 20, "scoped count")
+				scoped.RequireNotLogged(t, logging.LogQuery{HasFields: []string{logging.CollectorScopeKey}})
+
+				// The scoped logger is still a TLMLogger, so it can be passed along with its context
+				tlmLogger, ok := scopedLogger.(logging.TLMLogger)
+				util.AssertEqual(t, ok, true, "TLMLogger")
+				tlm.Log(tlmLogger.Context()).Info(fmt.Sprint("From context ", i))
+				scoped.RequireLogged(t, logging.LogQuery{Message: fmt.Sprint("From context ", i)})
+			})
+		}
+	})
+	util.AssertEqual(t, collector.GetNumberLogs(), 105, "total count")
+
+	collector.Clear()
+	outerLogger, outer := collector.Scope(logger)
+	innerLogger, inner := outer.Scope(outerLogger)
+	logger.Info("Unscoped")
+	outerLogger.Info("Outer")
+	innerLogger.Info("Inner")
+	util.AssertEqual(t, collector.GetNumberLogs(), 3, "root count")
+	util.AssertEqual(t, outer.GetNumberLogs(), 2, "outer count")
+	util.AssertEqual(t, inner.GetNumberLogs(), 1, "inner count")
+	util.AssertEqual(t, inner.GetMessage(0), "Inner", "inner message")
+
+	// Clearing a scope leaves the other logs
+	outer.Clear()
+	util.AssertEqual(t, collector.GetNumberLogs(), 1, "after clear")
+	util.AssertEqual(t, collector.GetMessage(0), "Unscoped", "left")
+}
+
+func TestDebugLogCollectorWait(t *testing.T) {
+	collector := logging.NewDebugLogCollector()
+
+	util.AssertEqual(t, collector.WaitForLogs(1, 10*time.Millisecond), false, "timeout")
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			collector.Write(&logging.Entry{Level: logging.InfoLevel, Message: fmt.Sprint("Async ", i)})
+		}
+	}()
+	entry, ok := collector.WaitForLog(logging.LogQuery{Message: "Async 2"}, 5*time.Second)
+	util.AssertEqual(t, ok, true, "wait for log")
+	util.AssertEqual(t, entry.Message, "Async 2", "message")
+	util.AssertEqual(t, collector.WaitForLogs(3, 0), true, "already logged")
+}
+
+// Only the collector sees the scope, not the primary logger or other sinks
+func TestDebugLogCollectorScopeNotWritten(t *testing.T) {
+	for _, logType := range []logging.LogType{logging.LogrusLogType, logging.SinkLogType} {
+		t.Run(logType.String(), func(t *testing.T) {
+			output := new(bytes.Buffer)
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{Type: logType, Output: output}
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			scopedLogger, scoped := collector.Scope(tlm.Log(ctx))
+			scopedLogger.WithField("kept", true).Info("Scoped")
+			scopedLogger.WithFields(util.Fields{logging.CollectorScopeKey: "other", "more": 1}).Info("Fields")
+
+			util.AssertEqual(t, scoped.GetNumberLogs(), 1, "scoped count")
+			util.AssertEqual(t, collector.GetNumberLogs(), 2, "count")
+			util.AssertContains(t, output.String(), "kept=true", "output")
+			util.AssertNotContains(t, output.String(), logging.CollectorScopeKey, "output")
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package logging
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Selects collected log entries. Every condition that's set must match
+type LogQuery struct {
+	// Exact level. DefaultLevel matches any level
+	Level LogLevel
+	// Lowest level to match. DefaultLevel matches any level
+	MinLevel LogLevel
+
+	// Exact message
+	Message string
+	// Message pattern, matched anywhere in the message
+	MessageRegex *regexp.Regexp
+
+	// Fields that must be set to these values. Errors match a string with the error's message
+	Fields util.Fields
+	// Fields that must be set, to any value
+	HasFields []string
+
+	// Time range, inclusive. Zero times aren't checked
+	Since time.Time
+	Until time.Time
+
+	// Value of the LoggerNameKey field
+	LoggerName string
+}
+
+// The subset of testing.TB used by the assertion helpers
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+func (q LogQuery) String() string {
+	conditions := make([]string, 0)
+	if q.Level != DefaultLevel {
+		conditions = append(conditions, "level="+q.Level.String())
+	}
+	if q.MinLevel != DefaultLevel {
+		conditions = append(conditions, "level>="+q.MinLevel.String())
+	}
+	if q.Message != "" {
+		conditions = append(conditions, fmt.Sprintf("msg=%q", q.Message))
+	}
+	if q.MessageRegex != nil {
+		conditions = append(conditions, fmt.Sprintf("msg=~/%s/", q.MessageRegex))
+	}
+	if q.LoggerName != "" {
+		conditions = append(conditions, fmt.Sprintf("%s=%q", LoggerNameKey, q.LoggerName))
+	}
+	for _, key := range sortedFieldKeys(q.Fields) {
+		conditions = append(conditions, fmt.Sprintf("%s=%v", key, q.Fields[key]))
+	}
+	for _, key := range q.HasFields {
+		conditions = append(conditions, key+"=*")
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "time>="+q.Since.Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "time<="+q.Until.Format(time.RFC3339Nano))
+	}
+	if len(conditions) == 0 {
+		return "any log"
+	}
+	return strings.Join(conditions, " ")
+}
+
+func (q LogQuery) Matches(entry *Entry) bool {
+	return q.mismatch(entry) == ""
+}
+
+func fieldValueMatches(expected, actual any) bool {
+	if err, ok := actual.(error); ok {
+		if str, ok := expected.(string); ok {
+			return err.Error() == str
+		}
+		if expectedErr, ok := expected.(error); ok {
+			return err == expectedErr || err.Error() == expectedErr.Error()
+		}
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// Get why the entry doesn't match, or an empty string if it does
+func (q LogQuery) mismatch(entry *Entry) string {
+	if q.Level != DefaultLevel && entry.Level != q.Level {
+		return fmt.Sprintf("level is %v, expected %v", entry.Level, q.Level)
+	}
+	if q.MinLevel != DefaultLevel && entry.Level.Severity() < q.MinLevel.Severity() {
+		return fmt.Sprintf("level is %v, expected at least %v", entry.Level, q.MinLevel)
+	}
+	if q.Message != "" && entry.Message != q.Message {
+		return fmt.Sprintf("msg is %q, expected %q", entry.Message, q.Message)
+	}
+	if q.MessageRegex != nil && !q.MessageRegex.MatchString(entry.Message) {
+		return fmt.Sprintf("msg %q doesn't match /%s/", entry.Message, q.MessageRegex)
+	}
+	if q.LoggerName != "" {
+		if name, ok := entry.Fields[LoggerNameKey]; !ok || name != q.LoggerName {
+			return fmt.Sprintf("%s is %v, expected %q", LoggerNameKey, name, q.LoggerName)
+		}
+	}
+	for _, key := range sortedFieldKeys(q.Fields) {
+		actual, ok := entry.Fields[key]
+		if !ok {
+			return fmt.Sprintf("field %s is missing", key)
+		}
+		if !fieldValueMatches(q.Fields[key], actual) {
+			return fmt.Sprintf("field %s is %v (%T), expected %v (%T)", key, actual, actual, q.Fields[key], q.Fields[key])
+		}
+	}
+	for _, key := range q.HasFields {
+		if _, ok := entry.Fields[key]; !ok {
+			return fmt.Sprintf("field %s is missing", key)
+		}
+	}
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return fmt.Sprintf("time %s is before %s", entry.Time.Format(time.RFC3339Nano), q.Since.Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() && entry.Time.After(q.Until) {
+		return fmt.Sprintf("time %s is after %s", entry.Time.Format(time.RFC3339Nano), q.Until.Format(time.RFC3339Nano))
+	}
+	return ""
+}
+
+// Get every collected entry that matches the query, in the order they were logged
+func (c *DebugLogCollector) Find(query LogQuery) []Entry {
+	entries := make([]Entry, 0)
+	for _, log := range c.snapshot() {
+		if query.Matches(&log.entry) {
+			entries = append(entries, log.entry)
+		}
+	}
+	return entries
+}
+
+// Describe collected logs for a failed assertion. Each log shows why it didn't match the query, if a query is given
+func describeLogs(logs []collectedLog, query *LogQuery) string {
+	if len(logs) == 0 {
+		return "no logs were collected"
+	}
+
+	formatter := newConsoleFormatter(Formatter{TimeKey: "-", Console: ConsoleOptions{Color: ConsoleColorNever}}, nil, util.SystemClock)
+	var b strings.Builder
+	b.WriteString("collected logs:")
+	for i := range logs {
+		line, _ := formatter.Format(&logs[i].entry)
+		lines := strings.Split(strings.TrimSuffix(string(line), "\n"), "\n")
+		fmt.Fprintf(&b, "\n  %d: %s", i, lines[0])
+		if query != nil {
+			if reason := query.mismatch(&logs[i].entry); reason != "" {
+				fmt.Fprintf(&b, "\n       - %s", reason)
+			} else {
+				b.WriteString("\n       + matches")
+			}
+		}
+		for _, extra := range lines[1:] {
+			b.WriteString("\n     " + extra)
+		}
+	}
+	return b.String()
+}
+
+// Fail the test unless a log matches the query. Returns the first matching entry
+func (c *DebugLogCollector) RequireLogged(t TestingT, query LogQuery) Entry {
+	t.Helper()
+	logs := c.snapshot()
+	for i := range logs {
+		if query.Matches(&logs[i].entry) {
+			return logs[i].entry
+		}
+	}
+	t.Fatalf("Expected a log matching: %v\n%s", query, describeLogs(logs, &query))
+	return Entry{}
+}
+
+// Fail the test if any log matches the query
+func (c *DebugLogCollector) RequireNotLogged(t TestingT, query LogQuery) {
+	t.Helper()
+	logs := c.snapshot()
+	for i := range logs {
+		if query.Matches(&logs[i].entry) {
+			t.Fatalf("Expected no log matching: %v, but log %d matched\n%s", query, i, describeLogs(logs, nil))
+			return
+		}
+	}
+}
+
+// Fail the test unless logs match the queries in order. Other logs can come before, after, and between the matches
+func (c *DebugLogCollector) RequireSequence(t TestingT, queries ...LogQuery) []Entry {
+	t.Helper()
+	logs := c.snapshot()
+	entries := make([]Entry, 0, len(queries))
+	next := 0
+	for q, query := range queries {
+		found := false
+		for ; next < len(logs); next++ {
+			if query.Matches(&logs[next].entry) {
+				entries = append(entries, logs[next].entry)
+				found = true
+				next++
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Expected log %d of the sequence after the previous match: %v\n%s", q, query, describeLogs(logs, &query))
+			return entries
+		}
+	}
+	return entries
+}
@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+const (
+	EcsVersion = "8.11.0"
+
+	// ECS requires UTC with millisecond precision
+	ecsTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+	defaultEcsNamespace  = "fields"
+	defaultEcsTraceIDKey = "trace_id"
+)
+
+// Options for the Elastic Common Schema format
+type EcsOptions struct {
+	// Written as service.name
+	ServiceName string
+	// The object fields are nested under. Default is "fields"
+	Namespace string
+	// The field that is written as trace.id. Default is "trace_id"
+	TraceIDKey string
+}
+
+// Formats entries with the Elastic Common Schema. Unlike the other formats, keys are fixed by the schema so the
+// formatter keys are ignored (except ErrorKey, to find the error recorded by WithError).
+type ecsFormatter struct {
+	formatter Formatter
+}
+
+func newEcsFormatter(formatter Formatter) *ecsFormatter {
+	return &ecsFormatter{
+		formatter: formatter,
+	}
+}
+
+func (e *ecsFormatter) Format(entry *Entry) ([]byte, error) {
+	options := e.formatter.Ecs
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = defaultEcsNamespace
+	}
+	traceIDKey := options.TraceIDKey
+	if traceIDKey == "" {
+		traceIDKey = defaultEcsTraceIDKey
+	}
+	errorKey := e.formatter.errorKey()
+
+	fields := make(util.Fields, len(entry.Fields))
+	for key, value := range entry.Fields {
+		fields[key] = value
+	}
+	takeField := func(key string) (any, bool) {
+		value, ok := fields[key]
+		delete(fields, key)
+		return value, ok
+	}
+
+	var b bytes.Buffer
+	w := &jsonObjectWriter{b: &b}
+	w.begin()
+	w.field("@timestamp", entry.Time.UTC().Format(ecsTimeFormat))
+	w.field("log.level", entry.Level.String())
+	w.field("message", entry.Message)
+	w.field("ecs.version", EcsVersion)
+
+	if entry.Caller != nil {
+		w.field("log", map[string]any{
+			"origin": map[string]any{
+				"function": entry.Caller.Function,
+				"file": map[string]any{
+					"name": entry.Caller.File,
+					"line": entry.Caller.Line,
+				},
+			},
+		})
+	}
+
+	ecsError := make(map[string]any)
+	if value, ok := takeField(errorKey); ok {
+		if err, ok := value.(error); ok {
+			ecsError["message"] = err.Error()
+			ecsError["type"] = fmt.Sprintf("%T", err)
+		} else {
+			ecsError["message"] = value
+		}
+	}
+	if value, ok := takeField(ErrorTypeKey); ok {
+		ecsError["type"] = value
+	}
+	if value, ok := takeField(ErrorStackKey); ok {
+		ecsError["stack_trace"] = value
+	}
+	if value, ok := takeField(ErrorCausesKey); ok {
+		ecsError["causes"] = value
+	}
+	if len(ecsError) > 0 {
+		w.field("error", ecsError)
+	}
+
+	if value, ok := takeField(traceIDKey); ok {
+		w.field("trace", map[string]any{"id": value})
+	}
+	if options.ServiceName != "" {
+		w.field("service", map[string]any{"name": options.ServiceName})
+	}
+	if len(fields) > 0 {
+		w.field(namespace, fields)
+	}
+	w.end()
+
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// Writes a JSON object with keys in the order they're written. Values are written with encoding/json
+type jsonObjectWriter struct {
+	b     *bytes.Buffer
+	count int
+}
+
+func (w *jsonObjectWriter) begin() {
+	w.b.WriteByte('{')
+}
+
+func (w *jsonObjectWriter) end() {
+	w.b.WriteByte('}')
+}
+
+func (w *jsonObjectWriter) field(key string, value any) {
+	if w.count > 0 {
+		w.b.WriteByte(',')
+	}
+	w.count++
+
+	keyData, _ := json.Marshal(key)
+	w.b.Write(keyData)
+	w.b.WriteByte(':')
+	w.b.Write(marshalJsonValue(value))
+}
+
+// Marshal a value, falling back to it's string form for values that can't be marshalled (like errors, which marshal to "{}")
+func marshalJsonValue(value any) []byte {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case util.Fields:
+		return marshalJsonMap(v)
+	case map[string]any:
+		return marshalJsonMap(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return data
+}
+
+func marshalJsonMap(values map[string]any) []byte {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	w := &jsonObjectWriter{b: &b}
+	w.begin()
+	for _, key := range keys {
+		w.field(key, values[key])
+	}
+	w.end()
+	return b.Bytes()
+}
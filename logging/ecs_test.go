@@ -0,0 +1,109 @@
+package logging_test
+
+import (
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+func getJsonPath(values map[string]any, path ...string) any {
+	var current any = values
+	for _, key := range path {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+	return current
+}
+
+func TestEcsFormat(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Formatter = logging.Formatter{
+		Type:        logging.EcsFormat,
+		FunctionKey: "~",
+		Ecs: logging.EcsOptions{
+			ServiceName: "tester",
+		},
+	}
+	logger, buffer := createLogger(logArgs)
+
+	logTime := time.Now()
+	logger.WithError(errors.New("bad thing")).WithFields(util.Fields{
+		"trace_id": "abc123",
+		"myField":  "value",
+	}).Warn("Hello ECS")
+
+	log := buffer.String()
+	util.AssertEqual(t, strings.HasPrefix(log, "{\"@timestamp\":"), true, "timestamp first")
+	util.AssertContains(t, log, "\"log.level\":\"warn\",\"message\":\"Hello ECS\",\"ecs.version\":", "ordered keys")
+
+	values := make(map[string]any)
+	util.AssertNoError(t, json.Unmarshal([]byte(log), &values), "json")
+
+	timestamp, _ := values["@timestamp"].(string)
+	util.AssertEqual(t, strings.HasSuffix(timestamp, "Z"), true, "UTC timestamp")
+	AssertTime(t, "@timestamp", "2006-01-02T15:04:05.000Z07:00", timestamp, log, logTime)
+
+	util.AssertEqual(t, values["ecs.version"], logging.EcsVersion, "version")
+	util.AssertEqual(t, getJsonPath(values, "log", "origin", "function"), "github.com/rcmaniac25/tlm/logging_test.TestEcsFormat", "function")
+	fileName, _ := getJsonPath(values, "log", "origin", "file", "name").(string)
+	util.AssertContains(t, fileName, "ecs_test.go", "file")
+	util.AssertNotEqual(t, getJsonPath(values, "log", "origin", "file", "line"), nil, "line")
+	util.AssertEqual(t, getJsonPath(values, "error", "message"), "bad thing", "error")
+	util.AssertEqual(t, getJsonPath(values, "error", "type"), "*errors.errorString", "error type")
+	util.AssertEqual(t, getJsonPath(values, "trace", "id"), "abc123", "trace")
+	util.AssertEqual(t, getJsonPath(values, "service", "name"), "tester", "service")
+	util.AssertEqual(t, getJsonPath(values, "fields", "myField"), "value", "field")
+	util.AssertEqual(t, getJsonPath(values, "fields", "trace_id"), nil, "trace moved")
+	util.AssertEqual(t, getJsonPath(values, "fields", "error"), nil, "error moved")
+	util.AssertEqual(t, getJsonPath(values, "fields", logging.ErrorTypeKey), nil, "error type moved")
+}
+
+func TestEcsFormatOptions(t *testing.T) {
+	formatter := logging.Formatter{
+		Type:     logging.EcsFormat,
+		ErrorKey: "err",
+		Ecs: logging.EcsOptions{
+			Namespace:  "app",
+			TraceIDKey: "traceId",
+		},
+	}
+
+	// Formatter can be used without logrus, for any backend
+	data, err := formatter.Format(&logging.Entry{
+		Time:    time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.FixedZone("test", 3600)),
+		Level:   logging.ErrorLevel,
+		Message: "Options",
+		Caller:  &runtime.Frame{Function: "main.main", File: "main.go", Line: 12},
+		Fields: util.Fields{
+			"err":                  errors.New("bad thing"),
+			logging.ErrorStackKey:  "stack",
+			"traceId":              "xyz",
+			"myField":              1,
+			"nested":               util.Fields{"b": 2, "a": 1},
+			"unmarshallable_field": func() {},
+		},
+	})
+	util.AssertNoError(t, err, "format")
+
+	expected := "{\"@timestamp\":\"2020-01-02T02:04:05.006Z\",\"log.level\":\"error\",\"message\":\"Options\",\"ecs.version\":\"" + logging.EcsVersion + "\"," +
+		"\"log\":{\"origin\":{\"file\":{\"line\":12,\"name\":\"main.go\"},\"function\":\"main.main\"}}," +
+		"\"error\":{\"message\":\"bad thing\",\"stack_trace\":\"stack\",\"type\":\"*errors.errorString\"}," +
+		"\"trace\":{\"id\":\"xyz\"}," +
+		"\"app\":{\"myField\":1,\"nested\":{\"a\":1,\"b\":2},\"unmarshallable_field\":"
+	util.AssertContains(t, string(data), expected, "output")
+}
+
+func TestFormatterNotImplemented(t *testing.T) {
+	formatter := logging.Formatter{Type: logging.TextFormat}
+	_, err := formatter.Format(&logging.Entry{})
+	util.AssertError(t, err, "text is implemented by the logger")
+}
@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// A log entry that isn't tied to any specific logger. Used by TLM's own formatters
+type Entry struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	// nil if the caller isn't being reported
+	Caller *runtime.Frame
+	Fields util.Fields
+	// Keys of Fields in the order they were added. Keys that aren't listed come after, sorted
+	FieldOrder []string
+
+	// Set by DebugLogCollector.Scope. Kept out of Fields so only the collector sees it
+	collectorScope string
+}
+
+// Formats entries, independent of any logger. Custom formatters implement this and are registered with RegisterFormatter
+type EntryFormatter interface {
+	Format(entry *Entry) ([]byte, error)
+}
@@ -0,0 +1,381 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// A logger that builds entries and writes them to sinks. Used for SinkLogType and for writing to sinks alongside other loggers
+type entryLogger struct {
+	settings *entryLoggerSettings
+	fields   util.Fields
+	order    []string
+	disabled bool // Set when V(n) is over the verbosity
+	// Frames skipped past the first frame outside of the logging packages, see WithCallerSkip
+	callerSkip int
+	// Custom logger that entries are written to along with the sinks, see EntryWriter
+	writer EntryWriter
+	// From DebugLogCollector.Scope, which only the collector sees
+	scope string
+	// Written instead of running the Before hooks, see withHookResult
+	hooked *hookResult
+}
+
+type entryLoggerSettings struct {
+	sinks        []Sink
+	level        LogLevel
+	verbosity    int
+	reportCaller bool
+	errorKey     string
+	clock        util.Clock
+
+	captureErrorStack bool
+
+	// When not set, panic and fatal logs are written but don't panic or exit. Used when another logger will do that
+	terminate bool
+	exitFunc  func(int)
+
+	// The writer keeps fields itself, see FieldWriter
+	writerFields bool
+
+	hooks hookChain
+	// Not set when writing to sinks alongside another logger, which runs the After hooks itself
+	afterHooks bool
+}
+
+func newEntryLogger(args *TLMLoggingInitialization, terminate bool) *entryLogger {
+	level := args.Level
+	if level == DefaultLevel {
+		level = InfoLevel
+	}
+	return &entryLogger{
+		settings: &entryLoggerSettings{
+			sinks:        args.Sinks,
+			level:        level,
+			verbosity:    args.Verbosity,
+			reportCaller: args.Formatter.reportCaller(),
+			errorKey:     args.Formatter.errorKey(),
+			clock:        args.clock(),
+			terminate:    terminate,
+			exitFunc:     os.Exit,
+
+			captureErrorStack: args.CaptureErrorStack,
+		},
+		fields: make(util.Fields),
+	}
+}
+
+// Initialize SinkLogType. When Output is set, formatted entries are written to it along with the sinks
+func initSinkLogger(args *TLMLoggingInitialization) (Logger, error) {
+	if len(args.Sinks) == 0 && args.Output == nil {
+		return nil, errors.New("type 'Sink' requires 'Sinks' or 'Output' to be set")
+	}
+	logger := newEntryLogger(args, true)
+	if args.Output != nil {
+		sink, err := newWriterSink(args.Output, args.Formatter, args.clock())
+		if err != nil {
+			return nil, err
+		}
+		logger.settings.sinks = append([]Sink{sink}, args.Sinks...)
+	}
+	return logger, nil
+}
+
+// Same as fmt.Sprintln, without the newline at the end
+func sprintln(args ...any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// Write an entry. Returns the entry once the Before hooks have run, nil if they dropped it, and false when they didn't
+// run
+func (e *entryLogger) write(level LogLevel, msg string) (*Entry, bool) {
+	if !e.Enabled(level) {
+		return nil, false
+	}
+
+	entry, hooked := e.hookedEntry(level, msg)
+	if entry == nil {
+		return nil, hooked
+	}
+
+	for _, sink := range e.settings.sinks {
+		if err := sink.Write(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+	}
+	if e.writer != nil {
+		writerEntry := entry
+		if e.settings.writerFields {
+			writerEntry = &Entry{Time: entry.Time, Level: entry.Level, Message: entry.Message, Caller: entry.Caller, Fields: make(util.Fields)}
+		}
+		if err := e.writer.Write(writerEntry); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+	}
+	if e.settings.afterHooks {
+		e.settings.hooks.after(entry)
+	}
+	return entry, hooked
+}
+
+// Create an entry and run the Before hooks on it, or use the result given by withHookResult
+func (e *entryLogger) hookedEntry(level LogLevel, msg string) (*Entry, bool) {
+	if e.hooked != nil {
+		if e.hooked.entry == nil {
+			return nil, true
+		}
+		entry := *e.hooked.entry
+		if !e.settings.reportCaller {
+			entry.Caller = nil
+		}
+		return &entry, true
+	}
+
+	entry := &Entry{
+		Time:    e.settings.clock.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  make(util.Fields, len(e.fields)),
+		// Loggers never change their order once created, so it can be shared
+		FieldOrder: e.order,
+
+		collectorScope: e.scope,
+	}
+	for key, value := range e.fields {
+		entry.Fields[key] = value
+	}
+	if e.settings.reportCaller {
+		if caller, ok := findCaller(e.callerSkip); ok {
+			entry.Caller = caller
+		}
+	}
+	if len(e.settings.hooks) == 0 {
+		return entry, false
+	}
+	if !e.settings.hooks.before(entry) {
+		return nil, true
+	}
+	return entry, true
+}
+
+// Flush the sinks and writer that buffer entries, before a panic or exit
+func (e *entryLogger) flush() {
+	targets := make([]any, 0, len(e.settings.sinks)+1)
+	for _, sink := range e.settings.sinks {
+		targets = append(targets, sink)
+	}
+	if e.writer != nil {
+		targets = append(targets, e.writer)
+	}
+	for _, target := range targets {
+		if flusher, ok := target.(Flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to flush log, %v\n", err)
+			}
+		}
+	}
+}
+
+func (e *entryLogger) derive(fields util.Fields) *entryLogger {
+	logger := &entryLogger{
+		settings:   e.settings,
+		fields:     make(util.Fields, len(e.fields)+len(fields)),
+		order:      e.order,
+		disabled:   e.disabled,
+		writer:     e.writer,
+		callerSkip: e.callerSkip,
+		scope:      e.scope,
+	}
+	if scope, ok := fields[CollectorScopeKey]; ok {
+		logger.scope, _ = scope.(string)
+		fields = withoutCollectorScope(fields)
+	}
+	if len(fields) > 0 {
+		logger.order = appendFieldOrder(e.order, sortedFieldKeys(fields)...)
+		if fieldWriter, ok := e.writer.(FieldWriter); ok {
+			logger.writer = fieldWriter.WithFields(fields)
+		}
+	}
+	for key, value := range e.fields {
+		logger.fields[key] = value
+	}
+	for key, value := range fields {
+		logger.fields[key] = value
+	}
+	return logger
+}
+
+func (e *entryLogger) panic(msg string) {
+	e.write(PanicLevel, msg)
+	if e.settings.terminate {
+		e.flush()
+		panic(msg)
+	}
+}
+
+func (e *entryLogger) fatal(msg string) {
+	e.write(FatalLevel, msg)
+	if e.settings.terminate {
+		e.flush()
+		e.settings.exitFunc(1)
+	}
+}
+
+func (e *entryLogger) WithExitFunc(exitFunc func(int)) Logger {
+	settings := *e.settings
+	settings.exitFunc = exitFunc
+	return &entryLogger{
+		settings:   &settings,
+		fields:     e.fields,
+		order:      e.order,
+		disabled:   e.disabled,
+		writer:     e.writer,
+		callerSkip: e.callerSkip,
+	}
+}
+
+func (e *entryLogger) WithHooks(hooks []Hook) Logger {
+	settings := *e.settings
+	settings.hooks = hooks
+	settings.afterHooks = true
+	return &entryLogger{
+		settings:   &settings,
+		fields:     e.fields,
+		order:      e.order,
+		disabled:   e.disabled,
+		writer:     e.writer,
+		callerSkip: e.callerSkip,
+	}
+}
+
+func (e *entryLogger) withHookResult(result *hookResult) Logger {
+	logger := e.derive(nil)
+	logger.hooked = result
+	return logger
+}
+
+func (e *entryLogger) WithCallerSkip(skip int) Logger {
+	logger := e.derive(nil)
+	logger.callerSkip += skip
+	return logger
+}
+
+// Hidden-function used for testing
+func (e *entryLogger) testExitFunc(exitHandler func(int)) bool {
+	e.settings.exitFunc = exitHandler
+	return true
+}
+
+func (e *entryLogger) WithField(key string, value any) Logger {
+	return e.derive(util.Fields{key: value})
+}
+
+func (e *entryLogger) WithFields(fields util.Fields) Logger {
+	return e.derive(fields)
+}
+
+func (e *entryLogger) WithError(err error) Logger {
+	if err == nil {
+		return e
+	}
+	return e.derive(errorFields(err, e.settings.errorKey, e.settings.captureErrorStack))
+}
+
+func (e *entryLogger) V(level int) Logger {
+	if level <= e.settings.verbosity {
+		return e
+	}
+	logger := e.derive(nil)
+	logger.disabled = true
+	return logger
+}
+
+func (e *entryLogger) Log(level LogLevel, args ...any) {
+	logAtLevel(e, level, args...)
+}
+func (e *entryLogger) Logf(level LogLevel, format string, args ...any) {
+	logfAtLevel(e, level, format, args...)
+}
+func (e *entryLogger) Enabled(level LogLevel) bool {
+	if e.disabled || level.Severity() == 0 {
+		return false
+	}
+	if enabler, ok := e.writer.(LevelEnabler); ok && !enabler.Enabled(level) {
+		return false
+	}
+	return level.Severity() >= e.settings.level.Severity()
+}
+
+func (e *entryLogger) Tracef(format string, args ...any) {
+	e.write(TraceLevel, fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Trace(args ...any) {
+	e.write(TraceLevel, fmt.Sprint(args...))
+}
+func (e *entryLogger) Traceln(args ...any) {
+	e.write(TraceLevel, sprintln(args...))
+}
+
+func (e *entryLogger) Debugf(format string, args ...any) {
+	e.write(DebugLevel, fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Debug(args ...any) {
+	e.write(DebugLevel, fmt.Sprint(args...))
+}
+func (e *entryLogger) Debugln(args ...any) {
+	e.write(DebugLevel, sprintln(args...))
+}
+
+func (e *entryLogger) Infof(format string, args ...any) {
+	e.write(InfoLevel, fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Info(args ...any) {
+	e.write(InfoLevel, fmt.Sprint(args...))
+}
+func (e *entryLogger) Infoln(args ...any) {
+	e.write(InfoLevel, sprintln(args...))
+}
+
+func (e *entryLogger) Warnf(format string, args ...any) {
+	e.write(WarnLevel, fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Warn(args ...any) {
+	e.write(WarnLevel, fmt.Sprint(args...))
+}
+func (e *entryLogger) Warnln(args ...any) {
+	e.write(WarnLevel, sprintln(args...))
+}
+
+func (e *entryLogger) Errorf(format string, args ...any) {
+	e.write(ErrorLevel, fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Error(args ...any) {
+	e.write(ErrorLevel, fmt.Sprint(args...))
+}
+func (e *entryLogger) Errorln(args ...any) {
+	e.write(ErrorLevel, sprintln(args...))
+}
+
+func (e *entryLogger) Panicf(format string, args ...any) {
+	e.panic(fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Panic(args ...any) {
+	e.panic(fmt.Sprint(args...))
+}
+func (e *entryLogger) Panicln(args ...any) {
+	e.panic(sprintln(args...))
+}
+
+func (e *entryLogger) Fatalf(format string, args ...any) {
+	e.fatal(fmt.Sprintf(format, args...))
+}
+func (e *entryLogger) Fatal(args ...any) {
+	e.fatal(fmt.Sprint(args...))
+}
+func (e *entryLogger) Fatalln(args ...any) {
+	e.fatal(sprintln(args...))
+}
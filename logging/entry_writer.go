@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// The least a custom logger has to implement. TLM provides the rest of Logger: every level function, fields, errors,
+// verbosity, exit and panic handling, and the caller. The writer can take over some of that by implementing the
+// optional interfaces below
+type EntryWriter interface {
+	Write(entry *Entry) error
+}
+
+// Writers that decide if the caller is reported, instead of Formatter.FunctionKey
+type CallerReporter interface {
+	ReportCaller() bool
+}
+
+// Writers that filter levels. Checked after TLMLoggingInitialization.Level, so trace logs still require TraceLevel
+type LevelEnabler interface {
+	Enabled(level LogLevel) bool
+}
+
+// Writers that buffer entries. Flushed before fatal logs exit and before panic logs panic
+type Flusher interface {
+	Flush() error
+}
+
+// Writers that keep fields themselves, such as to encode them once. Fields given to WithField(s) and WithError go to
+// WithFields, and entries written to the returned writer don't include them
+type FieldWriter interface {
+	WithFields(fields util.Fields) EntryWriter
+}
+
+// Writers that format entries with the configured Formatter. DefaultFormat is given as JsonFormat. Writers without
+// this can only be used with DefaultFormat
+type FormatterUser interface {
+	SetFormatter(formatter EntryFormatter) error
+}
+
+type CustomEntryWriterInitializationFunc func(args *TLMLoggingInitialization) (EntryWriter, error)
+
+func RegisterEntryWriter(typeName string, writerInit CustomEntryWriterInitializationFunc) error {
+	return RegisterEntryWriterWithInfo(LoggerInfo{Name: typeName}, writerInit)
+}
+
+// Register a custom logger that only implements EntryWriter. It's used the same way as loggers registered with
+// RegisterLogger
+func RegisterEntryWriterWithInfo(info LoggerInfo, writerInit CustomEntryWriterInitializationFunc) error {
+	if len(info.Name) == 0 {
+		return errors.New("typeName must be set")
+	}
+	if writerInit == nil {
+		return errors.New("writerInit cannot be nil")
+	}
+	return RegisterLoggerWithInfo(info, func(args *TLMLoggingInitialization) (Logger, error) {
+		writer, err := writerInit(args)
+		if err != nil {
+			return nil, err
+		}
+		if writer == nil {
+			return nil, nil
+		}
+		return initEntryWriterLogger(args, writer)
+	})
+}
+
+// Create a logger that provides everything the writer doesn't implement
+func initEntryWriterLogger(args *TLMLoggingInitialization, writer EntryWriter) (Logger, error) {
+	if formatterUser, ok := writer.(FormatterUser); ok {
+		formatter := args.Formatter
+		if formatter.Type == DefaultFormat {
+			formatter.Type = JsonFormat
+		}
+		entryFormatter, err := formatter.entryFormatter(args.Output, args.clock())
+		if err != nil {
+			return nil, err
+		}
+		if err := formatterUser.SetFormatter(entryFormatter); err != nil {
+			return nil, err
+		}
+	} else if args.Formatter.Type != DefaultFormat {
+		return nil, fmt.Errorf("logger does not support formatters: %v", args.CustomeType)
+	}
+
+	// Sinks are written to by InitLogging
+	logger := newEntryLogger(args, true)
+	logger.settings.sinks = nil
+	logger.writer = writer
+	if reporter, ok := writer.(CallerReporter); ok {
+		logger.settings.reportCaller = reporter.ReportCaller()
+	}
+	_, logger.settings.writerFields = writer.(FieldWriter)
+	return logger, nil
+}
@@ -0,0 +1,168 @@
+package logging_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Only implements EntryWriter
+type minimalWriter struct {
+	entries []logging.Entry
+}
+
+func (w *minimalWriter) Write(entry *logging.Entry) error {
+	w.entries = append(w.entries, *entry)
+	return nil
+}
+
+// Implements every optional interface
+type capableWriter struct {
+	*minimalWriter
+	fields    util.Fields
+	formatter logging.EntryFormatter
+	output    *bytes.Buffer
+	flushed   int
+}
+
+func (w *capableWriter) Write(entry *logging.Entry) error {
+	if err := w.minimalWriter.Write(entry); err != nil {
+		return err
+	}
+	line, err := w.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	w.output.Write(line)
+	return nil
+}
+func (w *capableWriter) ReportCaller() bool                  { return true }
+func (w *capableWriter) Enabled(level logging.LogLevel) bool { return level != logging.WarnLevel }
+func (w *capableWriter) Flush() error {
+	w.flushed++
+	return nil
+}
+func (w *capableWriter) SetFormatter(formatter logging.EntryFormatter) error {
+	w.formatter = formatter
+	return nil
+}
+func (w *capableWriter) WithFields(fields util.Fields) logging.EntryWriter {
+	derived := *w
+	derived.fields = make(util.Fields, len(w.fields)+len(fields))
+	for key, value := range w.fields {
+		derived.fields[key] = value
+	}
+	for key, value := range fields {
+		derived.fields[key] = value
+	}
+	return &derived
+}
+
+func startEntryWriter(t *testing.T, writer logging.EntryWriter, inits logging.TLMLoggingInitialization) (logging.TLMLogger, error) {
+	util.AssertNoError(t, logging.RegisterEntryWriter("EntryWriter", func(_ *logging.TLMLoggingInitialization) (logging.EntryWriter, error) {
+		return writer, nil
+	}), "register")
+	t.Cleanup(func() { logging.UnregisterLogger("EntryWriter") })
+
+	inits.Type = logging.CustomLogType
+	inits.CustomeType = "EntryWriter"
+	ctx, err := tlm.Startup(&tlm.TLMInitialization{Logging: &inits})
+	if err != nil {
+		return nil, err
+	}
+	return tlm.Log(ctx), nil
+}
+
+func TestEntryWriter(t *testing.T) {
+	writer := new(minimalWriter)
+	exitcodes := make([]int, 0)
+	logger, err := startEntryWriter(t, writer, logging.TLMLoggingInitialization{
+		Level:     logging.TraceLevel,
+		Formatter: logging.Formatter{FunctionKey: "~"},
+		ExitFunc:  func(code int) { exitcodes = append(exitcodes, code) },
+	})
+	util.AssertNoError(t, err, "startup")
+
+	derived := logger.WithField("request", 7)
+	derived.Tracef("%s", "trace")
+	derived.Debugln("debug")
+	derived.Info("info")
+	derived.V(1).Info("too verbose")
+	derived.WithFields(util.Fields{"extra": true}).Error("error")
+	util.AssertPanic(t, func() { derived.Panicf("panic %d", 1) }, "panic")
+	derived.Fatal("fatal")
+
+	util.AssertEqual(t, len(writer.entries), 6, "count")
+	util.AssertEqual(t, fmt.Sprint(exitcodes), "[1]", "exit codes")
+	for i, level := range []logging.LogLevel{logging.TraceLevel, logging.DebugLevel, logging.InfoLevel, logging.ErrorLevel, logging.PanicLevel, logging.FatalLevel} {
+		entry := writer.entries[i]
+		util.AssertEqualf(t, entry.Level, level, "level %d", i)
+		util.AssertEqualf(t, entry.Fields["request"], 7, "field %d", i)
+		util.AssertEqualf(t, entry.Caller != nil && strings.HasPrefix(entry.Caller.Function, "github.com/rcmaniac25/tlm/logging_test.TestEntryWriter"), true, "caller %d", i)
+	}
+	util.AssertEqual(t, writer.entries[1].Message, "debug", "message")
+	util.AssertEqual(t, writer.entries[3].Fields["extra"], true, "derived field")
+	util.AssertEqual(t, writer.entries[4].Message, "panic 1", "panic message")
+}
+
+func TestEntryWriterCapabilities(t *testing.T) {
+	writer := &capableWriter{minimalWriter: new(minimalWriter), output: new(bytes.Buffer)}
+	logger, err := startEntryWriter(t, writer, logging.TLMLoggingInitialization{
+		Formatter: logging.Formatter{Type: logging.LogfmtFormat, TimeKey: "-"},
+		ExitFunc:  func(int) {},
+	})
+	util.AssertNoError(t, err, "startup")
+
+	logger.Debug("below level")
+	logger.Warn("filtered by writer")
+	logger.WithField("native", 1).Info("native fields")
+	logger.Fatal("fatal")
+
+	util.AssertEqual(t, len(writer.entries), 2, "count")
+	util.AssertEqual(t, len(writer.entries[0].Fields), 0, "fields kept by writer")
+	util.AssertNotEqual(t, writer.entries[0].Caller, nil, "caller reported")
+	util.AssertEqual(t, writer.flushed, 1, "flushed before exit")
+	util.AssertContains(t, writer.output.String(), "level=info msg=\"native fields\" func=", "formatted")
+	util.AssertEqual(t, logger.Enabled(logging.WarnLevel), false, "warn enabled")
+	util.AssertEqual(t, logger.Enabled(logging.ErrorLevel), true, "error enabled")
+}
+
+func TestEntryWriterFormatterNotSupported(t *testing.T) {
+	_, err := startEntryWriter(t, new(minimalWriter), logging.TLMLoggingInitialization{
+		Formatter: logging.Formatter{Type: logging.JsonFormat},
+	})
+	util.AssertError(t, err, "formatter")
+}
+
+func TestRegisterEntryWriter(t *testing.T) {
+	util.AssertError(t, logging.RegisterEntryWriter("", func(_ *logging.TLMLoggingInitialization) (logging.EntryWriter, error) {
+		return nil, nil
+	}), "no name")
+	util.AssertError(t, logging.RegisterEntryWriter("NilWriter", nil), "no init func")
+
+	util.AssertNoError(t, logging.RegisterEntryWriter("NilWriter", func(_ *logging.TLMLoggingInitialization) (logging.EntryWriter, error) {
+		return nil, nil
+	}), "register")
+	defer logging.UnregisterLogger("NilWriter")
+	_, err := logging.InitLogging(&logging.TLMLoggingInitialization{Type: logging.CustomLogType, CustomeType: "NilWriter"})
+	util.AssertError(t, err, "no writer")
+}
+
+// Loggers that aren't from InitLogging, such as wrappers, still get a context and keep their fields
+func TestWrapLogger(t *testing.T) {
+	writer := new(minimalWriter)
+	logger, err := startEntryWriter(t, writer, logging.TLMLoggingInitialization{})
+	util.AssertNoError(t, err, "startup")
+
+	wrapped := logging.WrapLogger(struct{ logging.Logger }{logger})
+	util.AssertNotEqual(t, wrapped.Context(), nil, "context")
+	wrapped.WithField("wrapped", true).Info("Wrapped")
+	util.AssertEqual(t, len(writer.entries), 1, "count")
+	util.AssertEqual(t, writer.entries[0].Fields["wrapped"], true, "field")
+	util.AssertEqual(t, logging.WrapLogger(logger), logger, "not wrapped twice")
+}
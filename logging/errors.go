@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+const (
+	// Keys used by WithError in addition to the error key
+	ErrorTypeKey   = "error_type"
+	ErrorCausesKey = "causes"
+	ErrorStackKey  = "stack"
+
+	// Keys used for each cause
+	CauseMessageKey = "message"
+	CauseTypeKey    = "type"
+)
+
+// Build the fields that WithError adds to a logger
+func errorFields(err error, errorKey string, captureStack bool) util.Fields {
+	fields := util.Fields{
+		errorKey:     err.Error(),
+		ErrorTypeKey: fmt.Sprintf("%T", err),
+	}
+	if causes := errorCauses(err); len(causes) > 0 {
+		fields[ErrorCausesKey] = causes
+	}
+	if stack, ok := errorStack(err); ok {
+		fields[ErrorStackKey] = stack
+	} else if captureStack {
+		fields[ErrorStackKey] = callerStack()
+	}
+	return fields
+}
+
+// Unwrap an error into a list of causes. Single wrapped errors (errors.Unwrap) are flattened into the list while
+// joined errors (errors.Join, or any error with Unwrap() []error) are nested under each of the joined errors.
+func errorCauses(err error) []any {
+	causes := make([]any, 0)
+	for {
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, joined := range e.Unwrap() {
+				if joined == nil {
+					continue
+				}
+				cause := errorCause(joined)
+				if subCauses := errorCauses(joined); len(subCauses) > 0 {
+					cause[ErrorCausesKey] = subCauses
+				}
+				causes = append(causes, cause)
+			}
+			return causes
+		default:
+			err = errors.Unwrap(err)
+			if err == nil {
+				return causes
+			}
+			if _, ok := err.(interface{ Unwrap() []error }); ok {
+				// Keep the joined errors together under the error that joined them
+				cause := errorCause(err)
+				if subCauses := errorCauses(err); len(subCauses) > 0 {
+					cause[ErrorCausesKey] = subCauses
+				}
+				return append(causes, cause)
+			}
+			causes = append(causes, errorCause(err))
+		}
+	}
+}
+
+func errorCause(err error) util.Fields {
+	return util.Fields{
+		CauseMessageKey: err.Error(),
+		CauseTypeKey:    fmt.Sprintf("%T", err),
+	}
+}
+
+// Get the stack trace an error carries. The deepest stack in the chain is used as that is closest to where the error originated.
+// Supported are errors with:
+// - StackTrace() which returns a type that formats with "%+v" (github.com/pkg/errors)
+// - Stack() []byte (github.com/go-errors/errors)
+// - Callers() []uintptr
+func errorStack(err error) (string, bool) {
+	stack := ""
+	found := false
+	for ; err != nil; err = errors.Unwrap(err) {
+		if s, ok := singleErrorStack(err); ok {
+			stack = s
+			found = true
+		}
+	}
+	return stack, found
+}
+
+func singleErrorStack(err error) (string, bool) {
+	switch e := err.(type) {
+	case interface{ Stack() []byte }:
+		return string(e.Stack()), true
+	case interface{ Callers() []uintptr }:
+		return formatStack(e.Callers()), true
+	}
+
+	// The result type is package specific, so it can't be checked with a type switch
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return "", false
+	}
+	trace := fmt.Sprintf("%+v", method.Call(nil)[0].Interface())
+	return strings.TrimPrefix(trace, "\n"), true
+}
+
+// Capture the stack of whatever called into the logging package, or a package skipped when finding the caller
+func callerStack() string {
+	frames := runtime.CallersFrames(callers())
+
+	var b strings.Builder
+	inLogging := true
+	for {
+		f, more := frames.Next()
+		if !inLogging || !skipCallerPackage(getPackageName(f.Function)) {
+			inLogging = false
+			writeFrame(&b, f)
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// Format program counters the same way panics print their stack
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	if len(pcs) == 0 {
+		return ""
+	}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		writeFrame(&b, f)
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+func writeFrame(b *strings.Builder, f runtime.Frame) {
+	fmt.Fprintf(b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+}
@@ -0,0 +1,158 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+type stackError struct {
+	pcs []uintptr
+}
+
+func newStackError() error {
+	pcs := make([]uintptr, 10)
+	depth := runtime.Callers(1, pcs)
+	return &stackError{pcs: pcs[:depth]}
+}
+
+func (e *stackError) Error() string      { return "stack error" }
+func (e *stackError) Callers() []uintptr { return e.pcs }
+
+// Same as errors.Join, which needs a newer Go version
+type joinedError []error
+
+func (e joinedError) Error() string   { return fmt.Sprint([]error(e)) }
+func (e joinedError) Unwrap() []error { return e }
+
+func createErrorLogger(t *testing.T, setup func(*logging.TLMLoggingInitialization)) (logging.TLMLogger, *logging.DebugLogCollector) {
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = new(logging.TLMLoggingInitialization)
+
+	collector := logging.NewDebugLogCollector()
+	collector.SetupInitialization(inits.Logging)
+	if setup != nil {
+		setup(inits.Logging)
+	}
+
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+	return tlm.Log(ctx), collector
+}
+
+func getCauses(t *testing.T, collector *logging.DebugLogCollector, logIndex int) []any {
+	value, ok := collector.GetField(logIndex, logging.ErrorCausesKey)
+	util.AssertEqual(t, ok, true, "causes exist")
+	causes, ok := value.([]any)
+	util.AssertEqual(t, ok, true, "causes type")
+	return causes
+}
+
+func getCauseMessage(t *testing.T, cause any) string {
+	fields, ok := cause.(util.Fields)
+	util.AssertEqual(t, ok, true, "cause type")
+	msg, _ := fields[logging.CauseMessageKey].(string)
+	return msg
+}
+
+func TestWithError(t *testing.T) {
+	logger, collector := createErrorLogger(t, nil)
+
+	logger.WithError(errors.New("plain")).Error("Failed")
+	util.AssertEqual(t, collector.GetMessage(0), "Failed", "message")
+	util.AssertEqualExistsFunc(t, collector.GetFieldFunc(0, "error"), "plain", "error")
+	causes, _ := collector.GetField(0, logging.ErrorCausesKey)
+	util.AssertEqual(t, causes, nil, "no causes")
+	stack, _ := collector.GetField(0, logging.ErrorStackKey)
+	util.AssertEqual(t, stack, nil, "no stack")
+}
+
+func TestWithErrorNil(t *testing.T) {
+	logger, collector := createErrorLogger(t, nil)
+
+	util.AssertEqual(t, logger.WithError(nil), logging.Logger(logger), "same logger")
+	util.AssertEqual(t, logging.NullLogger.WithError(errors.New("ignored")), logging.Logger(&logging.NullLogger), "null logger")
+	util.AssertEqual(t, collector.GetNumberLogs(), 0, "count")
+}
+
+func TestWithErrorKey(t *testing.T) {
+	logger, collector := createErrorLogger(t, func(args *logging.TLMLoggingInitialization) {
+		args.Formatter.ErrorKey = "err"
+	})
+
+	logger.WithError(errors.New("plain")).Error("Failed")
+	util.AssertEqualExistsFunc(t, collector.GetFieldFunc(0, "err"), "plain", "error")
+	defaultErr, _ := collector.GetField(0, "error")
+	util.AssertEqual(t, defaultErr, nil, "default key")
+}
+
+func TestWithErrorCauses(t *testing.T) {
+	logger, collector := createErrorLogger(t, nil)
+
+	root := errors.New("root")
+	wrapped := fmt.Errorf("middle: %w", root)
+	logger.WithError(fmt.Errorf("top: %w", wrapped)).Error("Wrapped")
+
+	causes := getCauses(t, collector, 0)
+	util.AssertEqual(t, len(causes), 2, "cause count")
+	util.AssertEqual(t, getCauseMessage(t, causes[0]), "middle: root", "cause 0")
+	util.AssertEqual(t, getCauseMessage(t, causes[1]), "root", "cause 1")
+
+	logger.WithError(joinedError{errors.New("first"), wrapped}).Error("Joined")
+
+	causes = getCauses(t, collector, 1)
+	util.AssertEqual(t, len(causes), 2, "joined count")
+	util.AssertEqual(t, getCauseMessage(t, causes[0]), "first", "joined 0")
+	util.AssertEqual(t, getCauseMessage(t, causes[1]), "middle: root", "joined 1")
+
+	subCauses, ok := causes[1].(util.Fields)[logging.ErrorCausesKey].([]any)
+	util.AssertEqual(t, ok, true, "sub causes")
+	util.AssertEqual(t, len(subCauses), 1, "sub cause count")
+	util.AssertEqual(t, getCauseMessage(t, subCauses[0]), "root", "sub cause")
+}
+
+func TestWithErrorStack(t *testing.T) {
+	logger, collector := createErrorLogger(t, nil)
+
+	logger.WithError(fmt.Errorf("wrapped: %w", newStackError())).Error("Stack")
+
+	stack, ok := collector.GetField(0, logging.ErrorStackKey)
+	util.AssertEqual(t, ok, true, "stack")
+	util.AssertContains(t, stack.(string), "newStackError", "stack contents")
+}
+
+func TestWithErrorCallSiteStack(t *testing.T) {
+	logger, collector := createErrorLogger(t, func(args *logging.TLMLoggingInitialization) {
+		args.CaptureErrorStack = true
+	})
+
+	logger.WithError(errors.New("no stack")).Error("Stack")
+
+	stack, ok := collector.GetField(0, logging.ErrorStackKey)
+	util.AssertEqual(t, ok, true, "stack")
+	util.AssertContains(t, stack.(string), "TestWithErrorCallSiteStack", "stack contents")
+	util.AssertNotContains(t, stack.(string), logging.LoggingPackageName+".", "logging package")
+}
+
+func TestLogrusWithError(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger, err := logging.InitLogrus(&logging.TLMLoggingInitialization{
+		Output:            output,
+		Formatter:         logging.Formatter{Type: logging.JsonFormat, ErrorKey: "err"},
+		CaptureErrorStack: true,
+	})
+	util.AssertNoError(t, err, "init")
+
+	logger.WithField("derived", true).WithError(errors.New("plain")).Error("Failed")
+	var line map[string]any
+	util.AssertNoError(t, json.Unmarshal(output.Bytes(), &line), "json")
+	util.AssertEqual(t, line["err"], "plain", "error key")
+	util.AssertContains(t, fmt.Sprint(line[logging.ErrorStackKey]), "TestLogrusWithError", "stack")
+}
@@ -0,0 +1,229 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Values nested deeper than this aren't flattened
+	maxFlattenDepth = 10
+	// Written in place of values that contain themselves, which can't be formatted
+	flattenCycleValue = "<cycle>"
+)
+
+type FieldOrder int
+
+const (
+	// Fields are sorted by key
+	SortedFieldOrder FieldOrder = iota
+	// Fields are in the order they were added to the logger. Fields from a single WithFields call are sorted by key
+	InsertionFieldOrder
+)
+
+func (o FieldOrder) String() string {
+	switch o {
+	case SortedFieldOrder:
+		return "sorted"
+	case InsertionFieldOrder:
+		return "insertion"
+	}
+	return "unknown"
+}
+
+type field struct {
+	key   string
+	value any
+}
+
+// Add keys to the field order, without changing the order of keys that were already added. A new slice is returned so
+// loggers that share the original order aren't changed
+func appendFieldOrder(order []string, keys ...string) []string {
+	result := make([]string, len(order), len(order)+len(keys))
+	copy(result, order)
+	for _, key := range keys {
+		found := false
+		for _, existing := range result {
+			if existing == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// Get the keys of fields, sorted
+func sortedFieldKeys[T any](fields map[string]T) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// If the formatter changes the layout of fields. Only TLM's formatters support this
+func (f Formatter) customFieldLayout() bool {
+	return len(f.LeadingKeys) > 0 || f.FieldOrder != SortedFieldOrder || f.DataKey != "" || f.FlattenSeparator != "" ||
+		(f.LineKey != "" && f.LineKey != "-")
+}
+
+// Lay out the fields of an entry: flattened if requested, then leading keys, then the rest in the field order
+func (f Formatter) layoutFields(entry *Entry) []field {
+	var keys []string
+	if f.FieldOrder == InsertionFieldOrder {
+		keys = make([]string, 0, len(entry.Fields))
+		for _, key := range entry.FieldOrder {
+			if _, ok := entry.Fields[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+		// Fields that weren't tracked, such as those added by hooks, come after
+		ordered := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			ordered[key] = true
+		}
+		for _, key := range sortedFieldKeys(entry.Fields) {
+			if !ordered[key] {
+				keys = append(keys, key)
+			}
+		}
+	} else {
+		keys = sortedFieldKeys(entry.Fields)
+	}
+
+	fields := make([]field, 0, len(keys))
+	for _, key := range keys {
+		if f.FlattenSeparator != "" {
+			fields = flattenField(fields, key, entry.Fields[key], f.FlattenSeparator, 0, nil)
+		} else {
+			fields = append(fields, field{key: key, value: entry.Fields[key]})
+		}
+	}
+	if f.FlattenSeparator != "" && f.FieldOrder == SortedFieldOrder {
+		sort.SliceStable(fields, func(i, j int) bool {
+			return fields[i].key < fields[j].key
+		})
+	}
+
+	if len(f.LeadingKeys) == 0 {
+		return fields
+	}
+	leading := make([]field, 0, len(fields))
+	for _, key := range f.LeadingKeys {
+		for i, fieldItem := range fields {
+			if fieldItem.key == key {
+				leading = append(leading, fieldItem)
+				fields = append(fields[:i:i], fields[i+1:]...)
+				break
+			}
+		}
+	}
+	return append(leading, fields...)
+}
+
+// Values that are written as they are, instead of being flattened
+func isFlattenLeaf(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	if value.CanInterface() {
+		switch value.Interface().(type) {
+		case time.Time, error, fmt.Stringer, json.Marshaler:
+			return true
+		}
+	}
+	return false
+}
+
+// A pointer or map being flattened. The type is needed as a struct and its first field have the same address
+type flattenVisit struct {
+	pointer   uintptr
+	valueType reflect.Type
+}
+
+// Flatten maps and structs into fields with keys joined by the separator. Values nested deeper than maxFlattenDepth are
+// written as they are. visited has the pointers and maps being flattened, to find values that contain themselves
+func flattenField(fields []field, key string, value any, separator string, depth int, visited map[flattenVisit]bool) []field {
+	if depth >= maxFlattenDepth {
+		return append(fields, field{key: key, value: value})
+	}
+	v := reflect.ValueOf(value)
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !isFlattenLeaf(v) {
+		if v.IsNil() {
+			return append(fields, field{key: key, value: nil})
+		}
+		if v.Kind() == reflect.Pointer {
+			visit := flattenVisit{pointer: v.Pointer(), valueType: v.Type()}
+			if visited[visit] {
+				return append(fields, field{key: key, value: flattenCycleValue})
+			}
+			if visited == nil {
+				visited = make(map[flattenVisit]bool)
+			}
+			visited[visit] = true
+			defer delete(visited, visit)
+		}
+		v = v.Elem()
+	}
+	if isFlattenLeaf(v) {
+		return append(fields, field{key: key, value: value})
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Len() == 0 {
+			break
+		}
+		visit := flattenVisit{pointer: v.Pointer(), valueType: v.Type()}
+		if visited[visit] {
+			return append(fields, field{key: key, value: flattenCycleValue})
+		}
+		if visited == nil {
+			visited = make(map[flattenVisit]bool)
+		}
+		visited[visit] = true
+		defer delete(visited, visit)
+
+		mapKeys := v.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return mapKeys[i].String() < mapKeys[j].String()
+		})
+		for _, mapKey := range mapKeys {
+			fields = flattenField(fields, key+separator+mapKey.String(), v.MapIndex(mapKey).Interface(), separator, depth+1, visited)
+		}
+		return fields
+	case reflect.Struct:
+		structType := v.Type()
+		start := len(fields)
+		for i := 0; i < structType.NumField(); i++ {
+			structField := structType.Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			name := structField.Name
+			if tag, ok := structField.Tag.Lookup("json"); ok {
+				tagName := strings.Split(tag, ",")[0]
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			fields = flattenField(fields, key+separator+name, v.Field(i).Interface(), separator, depth+1, visited)
+		}
+		if len(fields) > start {
+			return fields
+		}
+	}
+	return append(fields, field{key: key, value: value})
+}
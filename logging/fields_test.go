@@ -0,0 +1,205 @@
+package logging_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+type fieldsTestUser struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Password string `json:"-"`
+	Admin    bool
+	internal bool
+}
+
+type fieldsTestNode struct {
+	Name string
+	Next *fieldsTestNode
+}
+
+func TestFieldLayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter logging.Formatter
+		logFunc   func(logging.Logger)
+		expected  string
+	}{
+		{
+			name:      "Leading Keys",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, LeadingKeys: []string{"request_id", "missing", "component"}},
+			logFunc: func(logger logging.Logger) {
+				logger.WithFields(util.Fields{"zebra": 1, "component": "db", "apple": 2, "request_id": "abc"}).Info("Leading")
+			},
+			expected: "level=info msg=Leading request_id=abc component=db apple=2 zebra=1\n",
+		},
+		{
+			name:      "Insertion Order",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FieldOrder: logging.InsertionFieldOrder},
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("zebra", 1).WithFields(util.Fields{"mango": 2, "apple": 3}).WithField("banana", 4).WithField("zebra", 5).Info("Inserted")
+			},
+			expected: "level=info msg=Inserted zebra=5 apple=3 mango=2 banana=4\n",
+		},
+		{
+			name:      "Insertion Order With Error",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FieldOrder: logging.InsertionFieldOrder, LeadingKeys: []string{"error"}},
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("zebra", 1).WithError(errors.New("failed")).Error("Error")
+			},
+			expected: "level=error msg=Error error=failed zebra=1 error_type=*errors.errorString\n",
+		},
+		{
+			name:      "Data Key",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, DataKey: "data"},
+			logFunc:   func(logger logging.Logger) { logger.WithField("msg", "field").WithField("user", "bob").Info("Data") },
+			expected:  "level=info msg=Data data.msg=field data.user=bob\n",
+		},
+		{
+			name:      "Flatten",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FlattenSeparator: "."},
+			logFunc: func(logger logging.Logger) {
+				logger.WithFields(util.Fields{
+					"user":    &fieldsTestUser{ID: 5, Name: "bob", Password: "secret", Admin: true},
+					"request": map[string]any{"path": "/", "headers": map[string]string{"accept": "*/*"}},
+					"empty":   map[string]any{},
+					"nil":     (*fieldsTestUser)(nil),
+					"tags":    []string{"a", "b"},
+				}).Info("Flat")
+			},
+			expected: "level=info msg=Flat empty=map[] nil=null request.headers.accept=*/* request.path=/ tags=\"[a b]\" user.Admin=true user.id=5 user.name=bob\n",
+		},
+		{
+			name: "Flatten Under Data Key",
+			formatter: logging.Formatter{
+				Type:             logging.LogfmtFormat,
+				DataKey:          "data",
+				FlattenSeparator: "_",
+				FieldOrder:       logging.InsertionFieldOrder,
+				LeadingKeys:      []string{"request_id"},
+			},
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("user", fieldsTestUser{ID: 5}).WithField("request_id", 1).Info("Both")
+			},
+			expected: "level=info msg=Both data_request_id=1 data_user_id=5 data_user_name=\"\" data_user_Admin=false\n",
+		},
+		{
+			name:      "Json",
+			formatter: logging.Formatter{Type: logging.JsonFormat, FieldOrder: logging.InsertionFieldOrder},
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("zebra", 1).WithField("msg", "field").WithField("err", errors.New("failed")).Warn("Ordered")
+			},
+			expected: "{\"level\":\"warn\",\"msg\":\"Ordered\",\"zebra\":1,\"fields.msg\":\"field\",\"err\":\"failed\"}\n",
+		},
+		{
+			name:      "Json Data Key",
+			formatter: logging.Formatter{Type: logging.JsonFormat, DataKey: "data", LeadingKeys: []string{"b"}},
+			logFunc: func(logger logging.Logger) {
+				logger.WithFields(util.Fields{"a": 1, "b": map[string]any{"z": true, "y": nil}, "msg": "field"}).Info("Nested")
+			},
+			expected: "{\"level\":\"info\",\"msg\":\"Nested\",\"data\":{\"b\":{\"y\":null,\"z\":true},\"a\":1,\"msg\":\"field\"}}\n",
+		},
+		{
+			name:      "Json Data Key Without Fields",
+			formatter: logging.Formatter{Type: logging.JsonFormat, DataKey: "data"},
+			logFunc:   func(logger logging.Logger) { logger.Info("Empty") },
+			expected:  "{\"level\":\"info\",\"msg\":\"Empty\"}\n",
+		},
+		{
+			name:      "Json Flatten",
+			formatter: logging.Formatter{Type: logging.JsonFormat, FlattenSeparator: "."},
+			logFunc:   func(logger logging.Logger) { logger.WithField("user", fieldsTestUser{ID: 5, Name: "bob"}).Info("Flat") },
+			expected:  "{\"level\":\"info\",\"msg\":\"Flat\",\"user.Admin\":false,\"user.id\":5,\"user.name\":\"bob\"}\n",
+		},
+		{
+			name:      "Flatten Cycle",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FlattenSeparator: "."},
+			logFunc: func(logger logging.Logger) {
+				cyclicMap := map[string]any{"a": 1}
+				cyclicMap["self"] = cyclicMap
+				node := &fieldsTestNode{Name: "n"}
+				node.Next = node
+				logger.WithFields(util.Fields{"m": cyclicMap, "n": node}).Info("Cycle")
+			},
+			expected: "level=info msg=Cycle m.a=1 m.self=<cycle> n.Name=n n.Next=<cycle>\n",
+		},
+		{
+			name:      "Flatten Depth",
+			formatter: logging.Formatter{Type: logging.LogfmtFormat, FlattenSeparator: "."},
+			logFunc: func(logger logging.Logger) {
+				deep := map[string]any{"leaf": 1}
+				for i := 0; i < 11; i++ {
+					deep = map[string]any{"d": deep}
+				}
+				logger.WithField("deep", deep).Info("Deep")
+			},
+			expected: "level=info msg=Deep deep.d.d.d.d.d.d.d.d.d.d=map[d:map[leaf:1]]\n",
+		},
+		{
+			name: "Console",
+			formatter: logging.Formatter{
+				Type:             logging.ConsoleFormat,
+				DataKey:          "data",
+				FlattenSeparator: ".",
+				LeadingKeys:      []string{"user.name"},
+			},
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("user", fieldsTestUser{ID: 5, Name: "bob"}).Info("Console")
+			},
+			expected: "INFO  Console user.name=bob user.Admin=false user.id=5\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logArgs := new(logging.TLMLoggingInitialization)
+			logArgs.Formatter = test.formatter
+			logArgs.Formatter.TimeKey = "-"
+			logger, buffer := createLogger(logArgs)
+
+			test.logFunc(logger)
+			util.AssertEqual(t, buffer.String(), test.expected, "output")
+		})
+	}
+}
+
+// Formats entries into a buffer
+type formattingSink struct {
+	formatter logging.Formatter
+	buffer    bytes.Buffer
+}
+
+func (s *formattingSink) Write(entry *logging.Entry) error {
+	data, err := s.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	s.buffer.Write(data)
+	return nil
+}
+
+func (s *formattingSink) Close() error {
+	return nil
+}
+
+func TestFieldLayoutSinkLogger(t *testing.T) {
+	sink := &formattingSink{formatter: logging.Formatter{
+		Type:        logging.JsonFormat,
+		TimeKey:     "-",
+		FieldOrder:  logging.InsertionFieldOrder,
+		LeadingKeys: []string{"request_id"},
+	}}
+	logger := startSinkLogger(t, sink, nil)
+
+	base := logger.WithField("zebra", 1)
+	base.WithFields(util.Fields{"mango": 2, "apple": 3}).WithField("request_id", "abc").Info("First")
+	// Deriving from the same logger doesn't share the order
+	base.WithField("banana", 4).Info("Second")
+
+	util.AssertEqual(t, sink.buffer.String(),
+		"{\"level\":\"info\",\"msg\":\"First\",\"request_id\":\"abc\",\"zebra\":1,\"apple\":3,\"mango\":2}\n"+
+			"{\"level\":\"info\",\"msg\":\"Second\",\"zebra\":1,\"banana\":4}\n", "output")
+}
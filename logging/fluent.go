@@ -0,0 +1,437 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// Field used to name a logger. Sinks that support it, like the Fluent sink, use it to identify where entries came from
+	LoggerNameKey = "logger"
+
+	DefaultFluentTag = "tlm"
+
+	// Messages waiting to be sent. Writes wait once there are this many
+	fluentQueueSize = 100
+)
+
+var errFluentClosed = errors.New("fluent sink is closed")
+
+type FluentMode int
+
+const (
+	// Each entry is sent by itself
+	FluentMessageMode FluentMode = iota
+	// Entries are batched and sent as an array
+	FluentForwardMode
+	// Entries are batched and sent as a single binary blob
+	FluentPackedForwardMode
+)
+
+func (m FluentMode) String() string {
+	switch m {
+	case FluentMessageMode:
+		return "message"
+	case FluentForwardMode:
+		return "forward"
+	case FluentPackedForwardMode:
+		return "packed-forward"
+	}
+	return "unknown"
+}
+
+type FluentOptions struct {
+	// "tcp" (default) or "unix"
+	Network string
+	Address string
+
+	// Default is DefaultFluentTag. When an entry has a LoggerNameKey field, it's appended to the tag: "<tag>.<logger name>"
+	Tag  string
+	Mode FluentMode
+
+	// Wait for the server to acknowledge each chunk of entries
+	RequireAck bool
+	// Default is 5 seconds
+	AckTimeout time.Duration
+
+	// Only used by the forward modes. Entries are sent once there are BatchSize of them (default 100), or FlushInterval
+	// has passed (default 1 second)
+	BatchSize     int
+	FlushInterval time.Duration
+
+	// Connecting and each write give up after this long. Default is 5 seconds
+	Timeout time.Duration
+
+	// How many times to reconnect and resend before giving up. Default is 5, negative values don't retry
+	MaxRetries int
+	// Wait before the first retry, doubling after each retry up to MaxRetryWait. Defaults are 100ms and 30s
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+}
+
+type fluentEvent struct {
+	time   time.Time
+	record map[string]any
+}
+
+type fluentMessage struct {
+	data  []byte
+	chunk string
+	// Set by Flush, which waits for the messages before it to be sent
+	flushed chan error
+}
+
+// Writes entries to Fluentd or Fluent Bit with the Forward protocol. Entries are sent, and resent, in the background.
+// Flush waits for them to be sent
+type FluentSink struct {
+	options FluentOptions
+
+	// Only used by the sending goroutine, or once it's done
+	conn   net.Conn
+	reader *bufio.Reader
+
+	lock   sync.Mutex
+	closed bool
+	queue  chan fluentMessage
+	sent   sync.WaitGroup
+
+	// Events waiting to be sent by the forward modes, by tag
+	pending      map[string][]fluentEvent
+	pendingCount int
+	done         chan struct{}
+	flushed      sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewFluentSink(options FluentOptions) (*FluentSink, error) {
+	if options.Network == "" {
+		options.Network = "tcp"
+	}
+	switch options.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported network for fluent: %s", options.Network)
+	}
+	if options.Address == "" {
+		return nil, errors.New("'Address' must be set")
+	}
+	if options.Mode < FluentMessageMode || options.Mode > FluentPackedForwardMode {
+		return nil, fmt.Errorf("unknown fluent mode: %d", options.Mode)
+	}
+	if options.Tag == "" {
+		options.Tag = DefaultFluentTag
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.AckTimeout == 0 {
+		options.AckTimeout = 5 * time.Second
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = 5
+	}
+	if options.RetryWait <= 0 {
+		options.RetryWait = 100 * time.Millisecond
+	}
+	if options.MaxRetryWait <= 0 {
+		options.MaxRetryWait = 30 * time.Second
+	}
+
+	sink := &FluentSink{
+		options: options,
+		queue:   make(chan fluentMessage, fluentQueueSize),
+		pending: make(map[string][]fluentEvent),
+		done:    make(chan struct{}),
+	}
+	if err := sink.connect(); err != nil {
+		return nil, err
+	}
+	sink.sent.Add(1)
+	go sink.sendLoop()
+	if options.Mode != FluentMessageMode {
+		sink.flushed.Add(1)
+		go sink.flushLoop()
+	}
+	return sink, nil
+}
+
+func (f *FluentSink) connect() error {
+	conn, err := net.DialTimeout(f.options.Network, f.options.Address, f.options.Timeout)
+	if err != nil {
+		return err
+	}
+	f.conn = conn
+	f.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (f *FluentSink) disconnect() {
+	if f.conn != nil {
+		f.conn.Close()
+		f.conn = nil
+		f.reader = nil
+	}
+}
+
+func (f *FluentSink) tag(entry *Entry) string {
+	if name, ok := entry.Fields[LoggerNameKey].(string); ok && name != "" {
+		return f.options.Tag + "." + name
+	}
+	return f.options.Tag
+}
+
+func fluentRecord(entry *Entry) map[string]any {
+	record := map[string]any{
+		"message": entry.Message,
+		"level":   entry.Level.String(),
+	}
+	if entry.Caller != nil {
+		record["file"] = entry.Caller.File
+		record["line"] = entry.Caller.Line
+		record["function"] = entry.Caller.Function
+	}
+	for key, value := range entry.Fields {
+		if key == LoggerNameKey {
+			continue
+		}
+		if _, ok := record[key]; ok {
+			key = "fields." + key
+		}
+		record[key] = value
+	}
+	return record
+}
+
+func (f *FluentSink) Write(entry *Entry) error {
+	tag := f.tag(entry)
+	event := fluentEvent{time: entry.Time, record: fluentRecord(entry)}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return errFluentClosed
+	}
+
+	if f.options.Mode == FluentMessageMode {
+		var b bytes.Buffer
+		chunk := f.chunkID()
+		writeMsgpackArrayHeader(&b, f.arrayLength(3))
+		writeMsgpackString(&b, tag)
+		writeMsgpackEventTime(&b, event.time)
+		writeMsgpackMap(&b, event.record)
+		f.writeOption(&b, chunk, 0)
+		f.queue <- fluentMessage{data: b.Bytes(), chunk: chunk}
+		return nil
+	}
+
+	f.pending[tag] = append(f.pending[tag], event)
+	f.pendingCount++
+	if f.pendingCount >= f.options.BatchSize {
+		f.flush()
+	}
+	return nil
+}
+
+// The array has an extra item for the options, which are only sent when needed
+func (f *FluentSink) arrayLength(length int) int {
+	if f.options.RequireAck || f.options.Mode == FluentPackedForwardMode {
+		return length + 1
+	}
+	return length
+}
+
+func (f *FluentSink) chunkID() string {
+	if !f.options.RequireAck {
+		return ""
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return base64.StdEncoding.EncodeToString(id)
+}
+
+func (f *FluentSink) writeOption(b *bytes.Buffer, chunk string, size int) {
+	option := make(map[string]any)
+	if chunk != "" {
+		option["chunk"] = chunk
+	}
+	if f.options.Mode == FluentPackedForwardMode {
+		option["size"] = size
+	}
+	if len(option) > 0 {
+		writeMsgpackMap(b, option)
+	}
+}
+
+// Queue the pending events to be sent. Must be called with the lock held
+func (f *FluentSink) flush() {
+	for tag, events := range f.pending {
+		var b bytes.Buffer
+		chunk := f.chunkID()
+		writeMsgpackArrayHeader(&b, f.arrayLength(2))
+		writeMsgpackString(&b, tag)
+
+		var entries bytes.Buffer
+		writeEntries := &b
+		if f.options.Mode == FluentPackedForwardMode {
+			writeEntries = &entries
+		} else {
+			writeMsgpackArrayHeader(&b, len(events))
+		}
+		for _, event := range events {
+			writeMsgpackArrayHeader(writeEntries, 2)
+			writeMsgpackEventTime(writeEntries, event.time)
+			writeMsgpackMap(writeEntries, event.record)
+		}
+		if f.options.Mode == FluentPackedForwardMode {
+			writeMsgpackBinary(&b, entries.Bytes())
+		}
+
+		f.writeOption(&b, chunk, len(events))
+		f.queue <- fluentMessage{data: b.Bytes(), chunk: chunk}
+	}
+
+	f.pending = make(map[string][]fluentEvent)
+	f.pendingCount = 0
+}
+
+func (f *FluentSink) flushLoop() {
+	defer f.flushed.Done()
+
+	ticker := time.NewTicker(f.options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			f.lock.Lock()
+			f.flush()
+			f.lock.Unlock()
+		}
+	}
+}
+
+// Send queued messages until the queue is closed. Errors are reported to Flush as well
+func (f *FluentSink) sendLoop() {
+	defer f.sent.Done()
+
+	var sendErr error
+	for message := range f.queue {
+		if message.flushed != nil {
+			message.flushed <- sendErr
+			sendErr = nil
+			continue
+		}
+		if err := f.send(message.data, message.chunk); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+			if sendErr == nil {
+				sendErr = err
+			}
+		}
+	}
+	f.disconnect()
+}
+
+// Send a message, reconnecting with an exponential backoff if it fails. Only called by the sending goroutine
+func (f *FluentSink) send(message []byte, chunk string) error {
+	wait := f.options.RetryWait
+	for attempt := 0; ; attempt++ {
+		err := f.sendOnce(message, chunk)
+		if err == nil {
+			return nil
+		}
+		f.disconnect()
+		if attempt >= f.options.MaxRetries {
+			return err
+		}
+
+		time.Sleep(wait)
+		wait *= 2
+		if wait > f.options.MaxRetryWait {
+			wait = f.options.MaxRetryWait
+		}
+	}
+}
+
+func (f *FluentSink) sendOnce(message []byte, chunk string) error {
+	if f.conn == nil {
+		if err := f.connect(); err != nil {
+			return err
+		}
+	}
+	if err := f.conn.SetWriteDeadline(time.Now().Add(f.options.Timeout)); err != nil {
+		return err
+	}
+	if _, err := f.conn.Write(message); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	if err := f.conn.SetReadDeadline(time.Now().Add(f.options.AckTimeout)); err != nil {
+		return err
+	}
+	response, err := readMsgpackValue(f.reader)
+	if err != nil {
+		return err
+	}
+	if ack, ok := response.(map[string]any); !ok || ack["ack"] != chunk {
+		return fmt.Errorf("unexpected fluent ack: %v", response)
+	}
+	return nil
+}
+
+// Wait for the entries written so far to be sent. Returns the first error sending them since the last Flush
+func (f *FluentSink) Flush() error {
+	f.lock.Lock()
+	if f.closed {
+		f.lock.Unlock()
+		return errFluentClosed
+	}
+	flushed := f.queueFlush()
+	f.lock.Unlock()
+	return <-flushed
+}
+
+// Queue the pending events and a message that's answered once they're sent. Must be called with the lock held
+func (f *FluentSink) queueFlush() chan error {
+	f.flush()
+	flushed := make(chan error, 1)
+	f.queue <- fluentMessage{flushed: flushed}
+	return flushed
+}
+
+// Sends any pending entries and closes the connection
+func (f *FluentSink) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.done)
+		f.flushed.Wait()
+
+		f.lock.Lock()
+		f.closed = true
+		flushed := f.queueFlush()
+		close(f.queue)
+		f.lock.Unlock()
+
+		f.closeErr = <-flushed
+		f.sent.Wait()
+	})
+	return f.closeErr
+}
@@ -0,0 +1,437 @@
+package logging_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Decode MessagePack, enough to check what the fluent sink sends. EventTime is decoded as time.Time
+func decodeMsgpack(r *bufio.Reader) (any, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	readUint := func(size int) uint64 {
+		data := make([]byte, size)
+		io.ReadFull(r, data)
+		var value uint64
+		for _, b := range data {
+			value = value<<8 | uint64(b)
+		}
+		return value
+	}
+	readBytes := func(length int) []byte {
+		data := make([]byte, length)
+		io.ReadFull(r, data)
+		return data
+	}
+	readArray := func(length int) (any, error) {
+		values := make([]any, length)
+		for i := range values {
+			if values[i], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	readMap := func(length int) (any, error) {
+		values := make(map[string]any, length)
+		for i := 0; i < length; i++ {
+			key, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			if values[key.(string)], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return readMap(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return readArray(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return string(readBytes(int(code & 0x1f))), nil
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		return readBytes(int(readUint(1 << (code - 0xc4)))), nil
+	case 0xd9, 0xda, 0xdb:
+		return string(readBytes(int(readUint(1 << (code - 0xd9))))), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return int64(readUint(1 << (code - 0xcc))), nil
+	case 0xd3:
+		return int64(readUint(8)), nil
+	case 0xcb:
+		return math.Float64frombits(readUint(8)), nil
+	case 0xdc, 0xdd:
+		return readArray(int(readUint(2 << (code - 0xdc))))
+	case 0xde, 0xdf:
+		return readMap(int(readUint(2 << (code - 0xde))))
+	case 0xd7:
+		if extType, _ := r.ReadByte(); extType != 0 {
+			return nil, fmt.Errorf("unexpected ext type: %d", extType)
+		}
+		data := readBytes(8)
+		return time.Unix(int64(binary.BigEndian.Uint32(data[:4])), int64(binary.BigEndian.Uint32(data[4:]))), nil
+	}
+	return nil, fmt.Errorf("unexpected msgpack type: 0x%x", code)
+}
+
+type fluentMessage struct {
+	tag    string
+	events [][]any
+	option map[string]any
+}
+
+// Decode a forward protocol message into its events, whatever mode it was sent with
+func decodeFluentMessage(t *testing.T, r *bufio.Reader) fluentMessage {
+	value, err := decodeMsgpack(r)
+	util.AssertNoError(t, err, "decode")
+	array := value.([]any)
+
+	// Message mode has the time and record, the other modes only have the entries. Options come after those
+	message := fluentMessage{tag: array[0].(string)}
+	optionIndex := 2
+	if _, ok := array[1].(time.Time); ok {
+		optionIndex = 3
+	}
+	if len(array) > optionIndex {
+		message.option = array[optionIndex].(map[string]any)
+	}
+	switch entries := array[1].(type) {
+	case time.Time:
+		message.events = [][]any{{entries, array[2]}}
+	case []any:
+		for _, event := range entries {
+			message.events = append(message.events, event.([]any))
+		}
+	case []byte:
+		packed := bufio.NewReader(bytes.NewReader(entries))
+		for {
+			event, err := decodeMsgpack(packed)
+			if err == io.EOF {
+				break
+			}
+			util.AssertNoError(t, err, "decode packed")
+			message.events = append(message.events, event.([]any))
+		}
+	}
+	return message
+}
+
+// Stand-in for Fluent Bit. Each connection handles a set number of messages, acknowledging them if asked to
+type fluentServer struct {
+	listener net.Listener
+	messages chan fluentMessage
+}
+
+func startFluentServer(t *testing.T, perConnection []int, ack []bool) *fluentServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+
+	server := &fluentServer{listener: listener, messages: make(chan fluentMessage, 10)}
+	go func() {
+		for c, count := range perConnection {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			for i := 0; i < count; i++ {
+				message := decodeFluentMessage(t, reader)
+				if ack[c] && message.option["chunk"] != nil {
+					var b bytes.Buffer
+					b.Write([]byte{0x81, 0xa3, 'a', 'c', 'k'})
+					chunk := message.option["chunk"].(string)
+					b.Write([]byte{0xa0 | byte(len(chunk))})
+					b.WriteString(chunk)
+					conn.Write(b.Bytes())
+				}
+				server.messages <- message
+			}
+			conn.Close()
+		}
+	}()
+	return server
+}
+
+func (s *fluentServer) next(t *testing.T) fluentMessage {
+	select {
+	case message := <-s.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	return fluentMessage{}
+}
+
+func TestFluentMessageMode(t *testing.T) {
+	server := startFluentServer(t, []int{2}, []bool{false})
+	defer server.listener.Close()
+
+	sink, err := logging.NewFluentSink(logging.FluentOptions{Address: server.listener.Addr().String(), Tag: "app"})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	logger := startSinkLogger(t, sink, nil)
+	logTime := time.Now()
+	logger.WithFields(util.Fields{"myField": "value", "count": 300, "message": "clash"}).Info("Hello fluent")
+	logger.WithField(logging.LoggerNameKey, "db").Warn("Named")
+
+	message := server.next(t)
+	util.AssertEqual(t, message.tag, "app", "tag")
+	util.AssertEqual(t, len(message.events), 1, "event count")
+	util.AssertEqual(t, message.option == nil, true, "no option")
+
+	eventTime := message.events[0][0].(time.Time)
+	util.AssertEqual(t, eventTime.Sub(logTime) < time.Second && eventTime.Sub(logTime) > -time.Second, true, "time")
+	record := message.events[0][1].(map[string]any)
+	util.AssertEqual(t, record["message"], "Hello fluent", "message")
+	util.AssertEqual(t, record["level"], "info", "level")
+	util.AssertEqual(t, record["myField"], "value", "field")
+	util.AssertEqual(t, record["count"], int64(300), "number field")
+	util.AssertEqual(t, record["fields.message"], "clash", "clashing field")
+
+	message = server.next(t)
+	util.AssertEqual(t, message.tag, "app.db", "logger name tag")
+	record = message.events[0][1].(map[string]any)
+	util.AssertEqual(t, record["message"], "Named", "message")
+	util.AssertEqual(t, record[logging.LoggerNameKey], nil, "logger name")
+}
+
+func TestFluentForwardModes(t *testing.T) {
+	tests := []struct {
+		name string
+		mode logging.FluentMode
+		ack  bool
+	}{
+		{
+			name: "Forward",
+			mode: logging.FluentForwardMode,
+		},
+		{
+			name: "PackedForward",
+			mode: logging.FluentPackedForwardMode,
+		},
+		{
+			name: "Forward Ack",
+			mode: logging.FluentForwardMode,
+			ack:  true,
+		},
+		{
+			name: "PackedForward Ack",
+			mode: logging.FluentPackedForwardMode,
+			ack:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := startFluentServer(t, []int{2}, []bool{test.ack})
+			defer server.listener.Close()
+
+			sink, err := logging.NewFluentSink(logging.FluentOptions{
+				Address:    server.listener.Addr().String(),
+				Mode:       test.mode,
+				RequireAck: test.ack,
+				BatchSize:  3,
+			})
+			util.AssertNoError(t, err, "sink")
+
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "First"}), "write")
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Second"}), "write")
+			// Filling the batch sends it
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Third"}), "write")
+
+			message := server.next(t)
+			util.AssertEqual(t, message.tag, logging.DefaultFluentTag, "tag")
+			util.AssertEqual(t, len(message.events), 3, "event count")
+			for i, expected := range []string{"First", "Second", "Third"} {
+				util.AssertEqual(t, message.events[i][1].(map[string]any)["message"], expected, "message")
+			}
+			if test.mode == logging.FluentPackedForwardMode {
+				util.AssertEqual(t, message.option["size"], int64(3), "size")
+			}
+			util.AssertEqual(t, message.option["chunk"] != nil, test.ack, "chunk")
+
+			// Closing sends what's left
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Last"}), "write")
+			util.AssertNoError(t, sink.Close(), "close")
+			message = server.next(t)
+			util.AssertEqual(t, len(message.events), 1, "event count")
+			util.AssertEqual(t, message.events[0][1].(map[string]any)["message"], "Last", "message")
+		})
+	}
+}
+
+func TestFluentFlushInterval(t *testing.T) {
+	server := startFluentServer(t, []int{1}, []bool{false})
+	defer server.listener.Close()
+
+	sink, err := logging.NewFluentSink(logging.FluentOptions{
+		Address:       server.listener.Addr().String(),
+		Mode:          logging.FluentForwardMode,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Flushed"}), "write")
+	message := server.next(t)
+	util.AssertEqual(t, message.events[0][1].(map[string]any)["message"], "Flushed", "message")
+}
+
+func TestFluentReconnect(t *testing.T) {
+	// The first connection is closed without acknowledging the message, so it has to be sent again
+	server := startFluentServer(t, []int{1, 1}, []bool{false, true})
+	defer server.listener.Close()
+
+	sink, err := logging.NewFluentSink(logging.FluentOptions{
+		Address:    server.listener.Addr().String(),
+		RequireAck: true,
+		RetryWait:  time.Millisecond,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Retried"}), "write")
+	first := server.next(t)
+	second := server.next(t)
+	util.AssertEqual(t, first.events[0][1].(map[string]any)["message"], "Retried", "first message")
+	util.AssertEqual(t, second.events[0][1].(map[string]any)["message"], "Retried", "resent message")
+	util.AssertNotEqual(t, first.option["chunk"], nil, "chunk")
+	util.AssertEqual(t, first.option["chunk"], second.option["chunk"], "same chunk")
+}
+
+func TestFluentAckTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+	go func() {
+		// Never acknowledge anything
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	sink, err := logging.NewFluentSink(logging.FluentOptions{
+		Address:    listener.Addr().String(),
+		RequireAck: true,
+		AckTimeout: 10 * time.Millisecond,
+		MaxRetries: -1,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	// Entries are sent in the background, so the error comes from Flush
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Lost"}), "write")
+	util.AssertError(t, sink.Flush(), "ack timeout")
+	util.AssertNoError(t, sink.Flush(), "error reported once")
+}
+
+func TestFluentClose(t *testing.T) {
+	for _, mode := range []logging.FluentMode{logging.FluentMessageMode, logging.FluentForwardMode} {
+		t.Run(mode.String(), func(t *testing.T) {
+			server := startFluentServer(t, []int{1}, []bool{false})
+			defer server.listener.Close()
+
+			sink, err := logging.NewFluentSink(logging.FluentOptions{Address: server.listener.Addr().String(), Mode: mode})
+			util.AssertNoError(t, err, "sink")
+
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Sent"}), "write")
+			util.AssertNoError(t, sink.Close(), "close")
+			util.AssertNoError(t, sink.Close(), "close twice")
+			util.AssertError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "Closed"}), "write after close")
+			util.AssertError(t, sink.Flush(), "flush after close")
+
+			message := server.next(t)
+			util.AssertEqual(t, message.events[0][1].(map[string]any)["message"], "Sent", "message")
+		})
+	}
+}
+
+func TestFluentOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options logging.FluentOptions
+	}{
+		{
+			name:    "No Address",
+			options: logging.FluentOptions{},
+		},
+		{
+			name:    "Unknown Network",
+			options: logging.FluentOptions{Network: "udp", Address: "127.0.0.1:24224"},
+		},
+		{
+			name:    "Unknown Mode",
+			options: logging.FluentOptions{Address: "127.0.0.1:24224", Mode: 5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := logging.NewFluentSink(test.options)
+			util.AssertError(t, err, fmt.Sprintf("options: %+v", test.options))
+		})
+	}
+}
+
+func TestFluentAlongsideLogrus(t *testing.T) {
+	server := startFluentServer(t, []int{2}, []bool{false})
+	defer server.listener.Close()
+
+	sink, err := logging.NewFluentSink(logging.FluentOptions{
+		Address:       server.listener.Addr().String(),
+		Mode:          logging.FluentForwardMode,
+		FlushInterval: time.Hour,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	exited := false
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:     logging.LogrusLogType,
+		Output:   io.Discard,
+		Sinks:    []logging.Sink{sink},
+		ExitFunc: func(int) { exited = true },
+	}
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	// Nothing else sends the batch before the sink is closed
+	util.AssertPanic(t, func() { tlm.Log(ctx).Panic("Panicked") }, "panic")
+	message := server.next(t)
+	util.AssertEqual(t, message.events[0][1].(map[string]any)["message"], "Panicked", "panic message")
+
+	tlm.Log(ctx).Fatal("Exited")
+	util.AssertEqual(t, exited, true, "exited")
+	message = server.next(t)
+	util.AssertEqual(t, message.events[0][1].(map[string]any)["message"], "Exited", "fatal message")
+}
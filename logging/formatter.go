@@ -1,5 +1,16 @@
 package logging
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
 type FormatterType int
 
 const (
@@ -7,6 +18,13 @@ const (
 
 	TextFormat
 	JsonFormat
+	LogfmtFormat
+	// Elastic Common Schema
+	EcsFormat
+	// Human friendly format for local development
+	ConsoleFormat
+	// Requires setting CustomType to a formatter registered with RegisterFormatter
+	CustomFormat
 )
 
 func (g FormatterType) String() string {
@@ -15,12 +33,22 @@ func (g FormatterType) String() string {
 		return "text"
 	case JsonFormat:
 		return "json"
+	case LogfmtFormat:
+		return "logfmt"
+	case EcsFormat:
+		return "ecs"
+	case ConsoleFormat:
+		return "console"
+	case CustomFormat:
+		return "custom"
 	}
 	return ""
 }
 
 type Formatter struct {
 	Type FormatterType
+	// Only used when Type is CustomFormat
+	CustomType string
 
 	// Set the keys used for each log entry. Some defaults available:
 	// "-" means the key is skipped (only certain fields supported)
@@ -28,9 +56,219 @@ type Formatter struct {
 	// ""  means the logger default is used
 	TimeKey     string // Can be skipped with "-"
 	MessageKey  string
-	LevelKey    string
-	FunctionKey string
+	LevelKey    string // Can be skipped with "-" when using LogfmtFormat
+	FunctionKey string // Can be skipped with "-"
+	FileKey     string // Can be skipped with "-"
+	LineKey     string // Can be skipped with "-". When not set, the line is part of the file. TextFormat and logrus' DefaultFormat can't write it separately
+	ErrorKey    string // Used by WithError
 
 	// Default of time.RFC3339 is used if not set
 	TimeFormat string
+
+	// Field layout, used by TLM's formatters (JsonFormat, LogfmtFormat, and ConsoleFormat). JsonFormat uses TLM's own
+	// JSON formatter when any of these, or a LineKey that isn't "-", are set.
+
+	// Fields written before all other fields, in this order
+	LeadingKeys []string
+	// Order of the remaining fields. Defaults to sorted by key
+	FieldOrder FieldOrder
+	// Put all fields under this key. With LogfmtFormat, it's used as a key prefix. Not used by ConsoleFormat
+	DataKey string
+	// Flatten nested maps and structs, joining keys with this separator ("user.id")
+	FlattenSeparator string
+
+	// Only used by EcsFormat
+	Ecs EcsOptions
+	// Only used by ConsoleFormat
+	Console ConsoleOptions
+}
+
+// Build the formatter TLM implements for the format type, or the registered formatter for CustomFormat, to format any
+// number of entries. Not all format types are implemented by TLM. ConsoleFormat checks output to decide if colors are
+// used, and shows RelativeTime from when it's built using clock. util.SystemClock is used when clock is nil
+func (f Formatter) NewEntryFormatter(output io.Writer, clock util.Clock) (EntryFormatter, error) {
+	if clock == nil {
+		clock = util.SystemClock
+	}
+	return f.entryFormatter(output, clock)
+}
+
+func (f Formatter) entryFormatter(output io.Writer, clock util.Clock) (EntryFormatter, error) {
+	switch f.Type {
+	case JsonFormat:
+		return newJsonFormatter(f), nil
+	case LogfmtFormat:
+		return newLogfmtFormatter(f), nil
+	case EcsFormat:
+		return newEcsFormatter(f), nil
+	case ConsoleFormat:
+		return newConsoleFormatter(f, output, clock), nil
+	case CustomFormat:
+		return newCustomFormatter(f)
+	}
+	return nil, fmt.Errorf("format is not implemented by TLM: %v", f.Type)
+}
+
+// Format an entry with the formatters that TLM implements (JsonFormat, LogfmtFormat, EcsFormat, and ConsoleFormat) or a
+// registered custom formatter. This allows any logger to use them. The formatter is built for each entry, so use
+// NewEntryFormatter to format more than one, such as with ConsoleOptions.RelativeTime
+func (f Formatter) Format(entry *Entry) ([]byte, error) {
+	formatter, err := f.entryFormatter(nil, util.SystemClock)
+	if err != nil {
+		return nil, err
+	}
+	return formatter.Format(entry)
+}
+
+type CustomFormatterInitializationFunc func(formatter Formatter) (EntryFormatter, error)
+
+var (
+	registeredFormattersLock sync.RWMutex
+	registeredFormatters     = make(map[string]CustomFormatterInitializationFunc)
+)
+
+// Register a formatter so it can be used by any logger with CustomFormat. The Formatter is passed to the initialization
+// function so the formatter can use the configured keys
+func RegisterFormatter(typeName string, formatterInit CustomFormatterInitializationFunc) error {
+	if len(typeName) == 0 {
+		return errors.New("typeName must be set")
+	}
+	if formatterInit == nil {
+		return errors.New("formatterInit cannot be nil")
+	}
+
+	registeredFormattersLock.Lock()
+	defer registeredFormattersLock.Unlock()
+	if _, ok := registeredFormatters[typeName]; ok {
+		return fmt.Errorf("formatter of type '%s' already registered", typeName)
+	}
+	registeredFormatters[typeName] = formatterInit
+	return nil
+}
+
+func UnregisterFormatter(typeName string) {
+	registeredFormattersLock.Lock()
+	defer registeredFormattersLock.Unlock()
+	delete(registeredFormatters, typeName)
+}
+
+func newCustomFormatter(f Formatter) (EntryFormatter, error) {
+	if len(f.CustomType) == 0 {
+		return nil, errors.New("format 'custom' requires 'CustomType' to be set")
+	}
+	registeredFormattersLock.RLock()
+	formatterInit, ok := registeredFormatters[f.CustomType]
+	registeredFormattersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("custom format is not registered: %s", f.CustomType)
+	}
+	formatter, err := formatterInit(f)
+	if err != nil {
+		return nil, err
+	}
+	if formatter == nil {
+		return nil, fmt.Errorf("custom format did not initialize a formatter: %s", f.CustomType)
+	}
+	return formatter, nil
+}
+
+// Get the key that errors are recorded under
+func (f Formatter) errorKey() string {
+	switch f.ErrorKey {
+	case "", "~", "-":
+		return "error"
+	}
+	return f.ErrorKey
+}
+
+// Default keys used by TLM's own formatters, matching the logrus defaults
+const (
+	defaultTimeKey     = "time"
+	defaultMessageKey  = "msg"
+	defaultLevelKey    = "level"
+	defaultFunctionKey = "func"
+	defaultFileKey     = "file"
+	defaultLineKey     = "line"
+)
+
+// Get the key to use for a formatter key. Returns false if the key should be skipped
+func resolveKey(key, tildeKey, defaultKey string) (string, bool) {
+	switch key {
+	case "-":
+		return "", false
+	case "~":
+		return tildeKey, true
+	case "":
+		return defaultKey, true
+	}
+	return key, true
+}
+
+func (f Formatter) timeKey() (string, bool) {
+	return resolveKey(f.TimeKey, "time", defaultTimeKey)
+}
+
+func (f Formatter) messageKey() string {
+	// Messages can't be skipped
+	key, ok := resolveKey(f.MessageKey, "message", defaultMessageKey)
+	if !ok {
+		return defaultMessageKey
+	}
+	return key
+}
+
+func (f Formatter) levelKey() (string, bool) {
+	return resolveKey(f.LevelKey, "level", defaultLevelKey)
+}
+
+func (f Formatter) functionKey() (string, bool) {
+	return resolveKey(f.FunctionKey, "function", defaultFunctionKey)
+}
+
+func (f Formatter) fileKey() (string, bool) {
+	return resolveKey(f.FileKey, "file", defaultFileKey)
+}
+
+// Returns false if the line is part of the file, or skipped
+func (f Formatter) lineKey() (string, bool) {
+	if f.LineKey == "" {
+		return "", false
+	}
+	return resolveKey(f.LineKey, "line", defaultLineKey)
+}
+
+// The caller is reported when any of its keys are set
+func (f Formatter) reportCaller() bool {
+	for _, key := range []string{f.FunctionKey, f.FileKey, f.LineKey} {
+		if key != "" && key != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the fields for the caller, in the order they're written
+func (f Formatter) callerFields(caller *runtime.Frame) []field {
+	fields := make([]field, 0, 3)
+	if key, ok := f.functionKey(); ok {
+		fields = append(fields, field{key: key, value: caller.Function})
+	}
+	if key, ok := f.fileKey(); ok {
+		if f.LineKey == "" {
+			fields = append(fields, field{key: key, value: fmt.Sprintf("%s:%d", caller.File, caller.Line)})
+		} else {
+			fields = append(fields, field{key: key, value: caller.File})
+		}
+	}
+	if key, ok := f.lineKey(); ok {
+		fields = append(fields, field{key: key, value: caller.Line})
+	}
+	return fields
+}
+
+func (f Formatter) timeFormat() string {
+	if f.TimeFormat == "" {
+		return time.RFC3339
+	}
+	return f.TimeFormat
 }
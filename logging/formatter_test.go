@@ -0,0 +1,125 @@
+package logging_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// A company style format: "LEVEL|message|key=value,..."
+type pipeFormatter struct {
+	separator string
+}
+
+func (p *pipeFormatter) Format(entry *logging.Entry) ([]byte, error) {
+	fields := make([]string, 0, len(entry.Fields))
+	for _, key := range []string{"user", "count"} {
+		if value, ok := entry.Fields[key]; ok {
+			fields = append(fields, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	return []byte(strings.ToUpper(entry.Level.String()) + p.separator + entry.Message + p.separator + strings.Join(fields, ",") + "\n"), nil
+}
+
+func registerPipeFormatter(t *testing.T) {
+	err := logging.RegisterFormatter("pipe", func(formatter logging.Formatter) (logging.EntryFormatter, error) {
+		separator := "|"
+		if formatter.MessageKey != "" {
+			separator = formatter.MessageKey
+		}
+		return &pipeFormatter{separator: separator}, nil
+	})
+	util.AssertNoError(t, err, "register")
+	t.Cleanup(func() {
+		logging.UnregisterFormatter("pipe")
+	})
+}
+
+func TestCustomFormatter(t *testing.T) {
+	registerPipeFormatter(t)
+
+	tests := []struct {
+		name      string
+		logType   logging.LogType
+		formatter logging.Formatter
+		expected  string
+	}{
+		{
+			name:      "Logrus",
+			logType:   logging.LogrusLogType,
+			formatter: logging.Formatter{Type: logging.CustomFormat, CustomType: "pipe"},
+			expected:  "WARN|Custom|user=bob,count=2\n",
+		},
+		{
+			name:      "Sink",
+			logType:   logging.SinkLogType,
+			formatter: logging.Formatter{Type: logging.CustomFormat, CustomType: "pipe"},
+			expected:  "WARN|Custom|user=bob,count=2\n",
+		},
+		{
+			name:      "Formatter Options",
+			logType:   logging.SinkLogType,
+			formatter: logging.Formatter{Type: logging.CustomFormat, CustomType: "pipe", MessageKey: ";"},
+			expected:  "WARN;Custom;user=bob,count=2\n",
+		},
+		{
+			name:      "Sink Default Format",
+			logType:   logging.SinkLogType,
+			formatter: logging.Formatter{TimeKey: "-"},
+			expected:  "level=warn msg=Custom count=2 user=bob\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{
+				Type:      test.logType,
+				Output:    buffer,
+				Formatter: test.formatter,
+			}
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			tlm.Log(ctx).WithField("user", "bob").WithField("count", 2).Warn("Custom")
+			util.AssertEqual(t, buffer.String(), test.expected, "output")
+		})
+	}
+
+	data, err := logging.Formatter{Type: logging.CustomFormat, CustomType: "pipe"}.Format(&logging.Entry{Level: logging.InfoLevel, Message: "Direct"})
+	util.AssertNoError(t, err, "format")
+	util.AssertEqual(t, string(data), "INFO|Direct|\n", "direct format")
+}
+
+func TestCustomFormatterErrors(t *testing.T) {
+	registerPipeFormatter(t)
+	util.AssertError(t, logging.RegisterFormatter("pipe", func(logging.Formatter) (logging.EntryFormatter, error) { return nil, nil }), "duplicate")
+	util.AssertError(t, logging.RegisterFormatter("", func(logging.Formatter) (logging.EntryFormatter, error) { return nil, nil }), "no name")
+	util.AssertError(t, logging.RegisterFormatter("nil", nil), "nil init")
+
+	util.AssertNoError(t, logging.RegisterFormatter("failing", func(logging.Formatter) (logging.EntryFormatter, error) {
+		return nil, errors.New("ohnoes")
+	}), "register failing")
+	defer logging.UnregisterFormatter("failing")
+	util.AssertNoError(t, logging.RegisterFormatter("empty", func(logging.Formatter) (logging.EntryFormatter, error) {
+		return nil, nil
+	}), "register empty")
+	defer logging.UnregisterFormatter("empty")
+
+	for _, customType := range []string{"", "unknown", "failing", "empty"} {
+		for _, logType := range []logging.LogType{logging.LogrusLogType, logging.SinkLogType} {
+			_, err := logging.InitLogging(&logging.TLMLoggingInitialization{
+				Type:      logType,
+				Output:    new(bytes.Buffer),
+				Formatter: logging.Formatter{Type: logging.CustomFormat, CustomType: customType},
+			})
+			util.AssertError(t, err, fmt.Sprintf("%v logger with custom type '%s'", logType, customType))
+		}
+	}
+}
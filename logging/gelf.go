@@ -0,0 +1,298 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	GelfVersion = "1.1"
+
+	// Graylog's recommended chunk size, fits in most networks' MTU
+	DefaultGelfChunkSize = 1420
+	gelfChunkHeaderSize  = 12
+	gelfMaxChunks        = 128
+)
+
+var (
+	gelfChunkMagic = []byte{0x1e, 0x0f}
+
+	gelfInvalidFieldChars = regexp.MustCompile(`[^\w\.\-]`)
+
+	errGelfNotConnected = errors.New("not connected to GELF server")
+	errGelfClosed       = errors.New("GELF sink is closed")
+)
+
+type GelfCompression int
+
+const (
+	GelfNoCompression GelfCompression = iota
+	GelfGzipCompression
+	GelfZlibCompression
+)
+
+func (c GelfCompression) String() string {
+	switch c {
+	case GelfNoCompression:
+		return "none"
+	case GelfGzipCompression:
+		return "gzip"
+	case GelfZlibCompression:
+		return "zlib"
+	}
+	return "unknown"
+}
+
+type GelfOptions struct {
+	// "udp" (default) or "tcp"
+	Network string
+	Address string
+
+	// Default is os.Hostname
+	Host string
+	// Only supported by UDP
+	Compression GelfCompression
+	// Max UDP packet size, messages larger then this are chunked. Default is DefaultGelfChunkSize
+	ChunkSize int
+
+	// Connecting and each write give up after this long. Default is 5 seconds
+	Timeout time.Duration
+}
+
+// Writes entries to Graylog using GELF. Fields are written as additional fields
+type GelfSink struct {
+	options GelfOptions
+
+	lock   sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func NewGelfSink(options GelfOptions) (*GelfSink, error) {
+	if options.Network == "" {
+		options.Network = "udp"
+	}
+	if options.Address == "" {
+		return nil, errors.New("'Address' must be set")
+	}
+	if options.ChunkSize == 0 {
+		options.ChunkSize = DefaultGelfChunkSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.ChunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("chunk size must be larger then %d", gelfChunkHeaderSize)
+	}
+	switch options.Network {
+	case "udp", "udp4", "udp6":
+	case "tcp", "tcp4", "tcp6":
+		if options.Compression != GelfNoCompression {
+			return nil, errors.New("GELF over TCP doesn't support compression")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported network for GELF: %s", options.Network)
+	}
+	if options.Host == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		options.Host = host
+	}
+
+	sink := &GelfSink{options: options}
+	if err := sink.connect(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (g *GelfSink) connect() error {
+	conn, err := net.DialTimeout(g.options.Network, g.options.Address, g.options.Timeout)
+	if err != nil {
+		g.conn = nil
+		return err
+	}
+	g.conn = conn
+	return nil
+}
+
+func (g *GelfSink) isTCP() bool {
+	return strings.HasPrefix(g.options.Network, "tcp")
+}
+
+// Build the GELF message for an entry
+func (g *GelfSink) message(entry *Entry) map[string]any {
+	message := map[string]any{
+		"version":       GelfVersion,
+		"host":          g.options.Host,
+		"short_message": entry.Message,
+		"timestamp":     float64(entry.Time.UnixNano()/int64(1000000)) / 1000,
+		"level":         syslogSeverity(entry.Level),
+	}
+	if idx := strings.IndexByte(entry.Message, '\n'); idx >= 0 {
+		message["short_message"] = entry.Message[:idx]
+		message["full_message"] = entry.Message
+	}
+	for key, value := range entry.Fields {
+		key = "_" + gelfInvalidFieldChars.ReplaceAllString(key, "_")
+		switch key {
+		case "_id":
+			// Reserved by Graylog
+			key = "__id"
+		case "_file", "_line", "_function":
+			// Reserved for the caller
+			if entry.Caller != nil {
+				key = "_" + key
+			}
+		}
+		message[key] = gelfFieldValue(value)
+	}
+	if entry.Caller != nil {
+		message["_file"] = entry.Caller.File
+		message["_line"] = entry.Caller.Line
+		message["_function"] = entry.Caller.Function
+	}
+	return message
+}
+
+// Additional fields can only be strings or numbers
+func gelfFieldValue(value any) any {
+	switch v := value.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case nil:
+		return ""
+	}
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
+
+func (g *GelfSink) Write(entry *Entry) error {
+	data, err := json.Marshal(g.message(entry))
+	if err != nil {
+		return err
+	}
+
+	if g.isTCP() {
+		// Messages are null-byte delimited
+		data = append(data, 0)
+	} else if data, err = g.compress(data); err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.closed {
+		return errGelfClosed
+	}
+
+	err = errGelfNotConnected
+	if g.conn != nil {
+		err = g.write(data)
+	}
+	if err != nil {
+		// The connection may have been dropped, try once more
+		if g.conn != nil {
+			g.conn.Close()
+		}
+		if err = g.connect(); err == nil {
+			err = g.write(data)
+		}
+	}
+	return err
+}
+
+func (g *GelfSink) write(data []byte) error {
+	// The lock is held while writing, so a stalled server can't block logging for long
+	if err := g.conn.SetWriteDeadline(time.Now().Add(g.options.Timeout)); err != nil {
+		return err
+	}
+	if g.isTCP() || len(data) <= g.options.ChunkSize {
+		_, err := g.conn.Write(data)
+		return err
+	}
+	return g.writeChunks(data)
+}
+
+func (g *GelfSink) compress(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch g.options.Compression {
+	case GelfGzipCompression:
+		w = gzip.NewWriter(&b)
+	case GelfZlibCompression:
+		w = zlib.NewWriter(&b)
+	default:
+		return data, nil
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (g *GelfSink) writeChunks(data []byte) error {
+	chunkDataSize := g.options.ChunkSize - gelfChunkHeaderSize
+	count := (len(data) + chunkDataSize - 1) / chunkDataSize
+	if count > gelfMaxChunks {
+		return fmt.Errorf("GELF message too large: %d bytes needs %d chunks, max is %d", len(data), count, gelfMaxChunks)
+	}
+
+	messageID := make([]byte, 8)
+	if _, err := rand.Read(messageID); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, g.options.ChunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * chunkDataSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunk = chunk[:0]
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, messageID...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[i*chunkDataSize:end]...)
+		if _, err := g.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GelfSink) Close() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.closed = true
+	if g.conn == nil {
+		return nil
+	}
+	err := g.conn.Close()
+	g.conn = nil
+	return err
+}
@@ -0,0 +1,343 @@
+package logging_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Read a GELF message from UDP, putting chunks back together and decompressing
+func readGelfUDP(t *testing.T, conn net.PacketConn, compression logging.GelfCompression) (map[string]any, int) {
+	packet := make([]byte, 65536)
+	chunks := make(map[int][]byte)
+	count := 1
+	packets := 0
+	var data []byte
+	for len(chunks) < count {
+		util.AssertNoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)), "deadline")
+		n, _, err := conn.ReadFrom(packet)
+		util.AssertNoError(t, err, "read packet")
+		packets++
+
+		if n > 12 && packet[0] == 0x1e && packet[1] == 0x0f {
+			count = int(packet[11])
+			chunks[int(packet[10])] = append([]byte(nil), packet[12:n]...)
+			continue
+		}
+		chunks[0] = append([]byte(nil), packet[:n]...)
+	}
+	for i := 0; i < count; i++ {
+		data = append(data, chunks[i]...)
+	}
+
+	var reader io.Reader = bytes.NewReader(data)
+	var err error
+	switch compression {
+	case logging.GelfGzipCompression:
+		reader, err = gzip.NewReader(reader)
+	case logging.GelfZlibCompression:
+		reader, err = zlib.NewReader(reader)
+	}
+	util.AssertNoError(t, err, "decompress")
+
+	message := make(map[string]any)
+	util.AssertNoError(t, json.NewDecoder(reader).Decode(&message), "decode")
+	return message, packets
+}
+
+func TestGelfUDP(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression logging.GelfCompression
+		chunkSize   int
+		message     string
+		minPackets  int
+	}{
+		{
+			name:       "Single",
+			message:    "Hello Graylog",
+			minPackets: 1,
+		},
+		{
+			name:        "Gzip",
+			compression: logging.GelfGzipCompression,
+			message:     "Hello Graylog",
+			minPackets:  1,
+		},
+		{
+			name:        "Zlib",
+			compression: logging.GelfZlibCompression,
+			message:     "Hello Graylog",
+			minPackets:  1,
+		},
+		{
+			name:       "Chunked",
+			chunkSize:  100,
+			message:    randomString(1000),
+			minPackets: 10,
+		},
+		{
+			name:        "Chunked Gzip",
+			compression: logging.GelfGzipCompression,
+			chunkSize:   100,
+			message:     randomString(1000),
+			minPackets:  2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+			util.AssertNoError(t, err, "listen")
+			defer listener.Close()
+
+			sink, err := logging.NewGelfSink(logging.GelfOptions{
+				Address:     listener.LocalAddr().String(),
+				Host:        "tester",
+				Compression: test.compression,
+				ChunkSize:   test.chunkSize,
+			})
+			util.AssertNoError(t, err, "sink")
+			defer sink.Close()
+
+			logger := startSinkLogger(t, sink, nil)
+			logger.WithFields(util.Fields{
+				"myField": "value",
+				"count":   12,
+				"id":      "reserved",
+				"bad key": true,
+			}).Warn(test.message)
+
+			message, packets := readGelfUDP(t, listener, test.compression)
+			util.AssertEqual(t, packets >= test.minPackets, true, "packet count")
+			util.AssertEqual(t, message["version"], logging.GelfVersion, "version")
+			util.AssertEqual(t, message["host"], "tester", "host")
+			util.AssertEqual(t, message["short_message"], test.message, "message")
+			util.AssertEqual(t, message["level"], float64(4), "level")
+			util.AssertEqual(t, message["_myField"], "value", "field")
+			util.AssertEqual(t, message["_count"], float64(12), "number field")
+			util.AssertEqual(t, message["__id"], "reserved", "id field")
+			util.AssertEqual(t, message["_bad_key"], "true", "sanitized field")
+			_, hasTimestamp := message["timestamp"].(float64)
+			util.AssertEqual(t, hasTimestamp, true, "timestamp")
+		})
+	}
+}
+
+func randomString(length int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func TestGelfTooManyChunks(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+
+	sink, err := logging.NewGelfSink(logging.GelfOptions{
+		Address:   listener.LocalAddr().String(),
+		ChunkSize: 20,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	err = sink.Write(&logging.Entry{Message: randomString(2000)})
+	util.AssertError(t, err, "too many chunks")
+}
+
+func TestGelfTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		messages := make([]string, 0)
+		for len(messages) < 2 {
+			message, err := reader.ReadString(0)
+			if err != nil {
+				break
+			}
+			messages = append(messages, message[:len(message)-1])
+		}
+		received <- messages
+	}()
+
+	sink, err := logging.NewGelfSink(logging.GelfOptions{
+		Network: "tcp",
+		Address: listener.Addr().String(),
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	// Sinks can be used along side other loggers
+	logger := startSinkLogger(t, sink, func(args *logging.TLMLoggingInitialization) {
+		args.Type = logging.LogrusLogType
+		args.Output = io.Discard
+		args.Formatter.FunctionKey = "~"
+	})
+	logger.WithField("file", "user file").Info("First")
+	logger.Error("Second\nWith more details")
+
+	var messages []string
+	select {
+	case messages = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for messages")
+	}
+	util.AssertEqual(t, len(messages), 2, "message count")
+
+	first := make(map[string]any)
+	util.AssertNoError(t, json.Unmarshal([]byte(messages[0]), &first), "decode")
+	util.AssertEqual(t, first["short_message"], "First", "message")
+	util.AssertEqual(t, first["level"], float64(6), "level")
+	util.AssertEqual(t, first["_function"], "github.com/rcmaniac25/tlm/logging_test.TestGelfTCP", "function")
+	// Fields that collide with the caller keep their value under another key
+	util.AssertContains(t, first["_file"].(string), "gelf_test.go", "file")
+	util.AssertEqual(t, first["__file"], "user file", "user file")
+
+	second := make(map[string]any)
+	util.AssertNoError(t, json.Unmarshal([]byte(messages[1]), &second), "decode")
+	util.AssertEqual(t, second["short_message"], "Second", "message")
+	util.AssertEqual(t, second["full_message"], "Second\nWith more details", "full message")
+	util.AssertEqual(t, second["level"], float64(3), "level")
+}
+
+func TestGelfTCPReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		// Drop the first connection, then read from the next one
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Close()
+		conn, err = listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		message, err := bufio.NewReader(conn).ReadString(0)
+		if err == nil {
+			received <- message
+		}
+	}()
+
+	sink, err := logging.NewGelfSink(logging.GelfOptions{
+		Network: "tcp",
+		Address: listener.Addr().String(),
+	})
+	util.AssertNoError(t, err, "sink")
+
+	// Writes to a dropped connection can succeed until the other side resets it
+	entry := &logging.Entry{Level: logging.InfoLevel, Time: time.Now(), Message: "Reconnected"}
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		sink.Write(entry)
+		select {
+		case message := <-received:
+			util.AssertContains(t, message, "Reconnected", "message")
+			done = true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Timed out waiting for reconnect")
+		}
+	}
+
+	util.AssertNoError(t, sink.Close(), "close")
+	util.AssertError(t, sink.Write(entry), "write after close")
+	util.AssertNoError(t, sink.Close(), "close twice")
+}
+
+func TestGelfTCPWriteTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+	stalled := make(chan net.Conn, 2)
+	go func() {
+		// Accept connections but never read from them
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			stalled <- conn
+		}
+	}()
+	defer func() {
+		for len(stalled) > 0 {
+			(<-stalled).Close()
+		}
+	}()
+
+	sink, err := logging.NewGelfSink(logging.GelfOptions{
+		Network: "tcp",
+		Address: listener.Addr().String(),
+		Timeout: 20 * time.Millisecond,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	// Larger than the socket buffers, so the write and the retry on a new connection both time out instead of blocking
+	entry := &logging.Entry{Level: logging.InfoLevel, Time: time.Now(), Message: strings.Repeat("x", 32<<20)}
+	start := time.Now()
+	util.AssertError(t, sink.Write(entry), "write")
+	util.AssertEqual(t, time.Since(start) < 2*time.Second, true, "timed out")
+}
+
+func TestGelfOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options logging.GelfOptions
+	}{
+		{
+			name:    "No Address",
+			options: logging.GelfOptions{},
+		},
+		{
+			name:    "TCP Compression",
+			options: logging.GelfOptions{Network: "tcp", Address: "127.0.0.1:1", Compression: logging.GelfGzipCompression},
+		},
+		{
+			name:    "Unknown Network",
+			options: logging.GelfOptions{Network: "carrier-pigeon", Address: "127.0.0.1:1"},
+		},
+		{
+			name:    "Small Chunks",
+			options: logging.GelfOptions{Address: "127.0.0.1:1", ChunkSize: 12},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := logging.NewGelfSink(test.options)
+			util.AssertError(t, err, fmt.Sprintf("options: %+v", test.options))
+		})
+	}
+}
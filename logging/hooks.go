@@ -0,0 +1,128 @@
+package logging
+
+// Hooks see every entry, whatever the logger type. Set them with TLMLoggingInitialization.Hooks, where they run in order
+type Hook interface {
+	// Levels the hook runs for. Empty for every level
+	Levels() []LogLevel
+	// Called before the entry is formatted and written. The entry can be changed, and returning false drops it. Panic
+	// and fatal logs still panic and exit when their entry is dropped or its level is changed
+	Before(entry *Entry) bool
+	// Called after the entry is written, for side effects such as alerting. The entry must not be changed
+	After(entry *Entry)
+}
+
+// A Hook made of functions. Functions that aren't set do nothing
+type HookFuncs struct {
+	LogLevels  []LogLevel
+	BeforeFunc func(entry *Entry) bool
+	AfterFunc  func(entry *Entry)
+}
+
+func (h HookFuncs) Levels() []LogLevel {
+	return h.LogLevels
+}
+
+func (h HookFuncs) Before(entry *Entry) bool {
+	if h.BeforeFunc == nil {
+		return true
+	}
+	return h.BeforeFunc(entry)
+}
+
+func (h HookFuncs) After(entry *Entry) {
+	if h.AfterFunc != nil {
+		h.AfterFunc(entry)
+	}
+}
+
+// Loggers that run hooks. Custom loggers implement this to support TLMLoggingInitialization.Hooks. It's called during
+// initialization, so it may change the logger it's called on. When Sinks are also set, the Before hooks run for the
+// sinks, and the logger is given hooks that only run After along with the fields the Before hooks set
+type HookLogger interface {
+	// Get a logger that runs the hooks, as do all loggers derived from it
+	WithHooks(hooks []Hook) Logger
+}
+
+type hookChain []Hook
+
+func hookRunsFor(hook Hook, level LogLevel) bool {
+	levels := hook.Levels()
+	if len(levels) == 0 {
+		return true
+	}
+	for _, hookLevel := range levels {
+		if hookLevel == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Run the Before hooks. Returns false if the entry was dropped
+func (c hookChain) before(entry *Entry) bool {
+	for _, hook := range c {
+		if !hookRunsFor(hook, entry.Level) {
+			continue
+		}
+		if !hook.Before(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c hookChain) after(entry *Entry) {
+	for _, hook := range c {
+		if hookRunsFor(hook, entry.Level) {
+			hook.After(entry)
+		}
+	}
+}
+
+// The result of the Before hooks, which a teeLogger gives the primary logger so the hooks only run once for both
+type hookResult struct {
+	// The level logged at, before the hooks
+	level LogLevel
+	// Not set when the entry was dropped
+	entry *Entry
+}
+
+// Loggers that can write the result of the Before hooks instead of running them
+type hookResultLogger interface {
+	withHookResult(result *hookResult) Logger
+}
+
+// Get a logger that writes the result of the Before hooks. Loggers that can't be given it only get the fields set by
+// the hooks, and skip entries the hooks drop unless they would panic or exit
+func withHookResult(logger Logger, result *hookResult) Logger {
+	if resultLogger, ok := logger.(hookResultLogger); ok {
+		return resultLogger.withHookResult(result)
+	}
+	if result.entry == nil {
+		if result.level == PanicLevel || result.level == FatalLevel {
+			return logger
+		}
+		return &NullLogger
+	}
+	if len(result.entry.Fields) == 0 {
+		return logger
+	}
+	return logger.WithFields(result.entry.Fields)
+}
+
+// Only runs the After hook, for loggers given the result of the Before hooks by withHookResult
+type afterHook struct {
+	Hook
+}
+
+func (h afterHook) Before(entry *Entry) bool {
+	return true
+}
+
+func afterHooks(hooks []Hook) []Hook {
+	after := make([]Hook, len(hooks))
+	for i, hook := range hooks {
+		after[i] = afterHook{Hook: hook}
+	}
+	return after
+}
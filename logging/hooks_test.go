@@ -0,0 +1,216 @@
+package logging_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+func TestHooks(t *testing.T) {
+	for _, logType := range []logging.LogType{logging.LogrusLogType, logging.SinkLogType} {
+		t.Run(logType.String(), func(t *testing.T) {
+			output := new(bytes.Buffer)
+			written := make([]string, 0)
+			errorsSeen := make([]string, 0)
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{
+				Type:      logType,
+				Output:    output,
+				Formatter: logging.Formatter{Type: logging.LogfmtFormat, TimeKey: "-"},
+				Hooks: []logging.Hook{
+					logging.HookFuncs{
+						BeforeFunc: func(entry *logging.Entry) bool {
+							if strings.Contains(entry.Message, "drop") {
+								return false
+							}
+							delete(entry.Fields, "secret")
+							entry.Fields["added"] = true
+							if entry.Message == "Downgrade" {
+								entry.Level = logging.WarnLevel
+							}
+							return true
+						},
+						AfterFunc: func(entry *logging.Entry) {
+							// The entry has been written by the time After runs
+							written = append(written, fmt.Sprintf("%s:%v", entry.Message, strings.Contains(output.String(), entry.Message)))
+						},
+					},
+					logging.HookFuncs{
+						LogLevels: []logging.LogLevel{logging.ErrorLevel},
+						AfterFunc: func(entry *logging.Entry) {
+							errorsSeen = append(errorsSeen, entry.Message)
+						},
+					},
+				},
+			}
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			tlm.Log(ctx).WithField("secret", "hunter2").Info("Kept")
+			tlm.Log(ctx).Info("Please drop this")
+			tlm.Log(ctx).Error("Downgrade")
+			tlm.Log(ctx).Error("Failed")
+
+			util.AssertEqual(t, output.String(), "level=info msg=Kept added=true\nlevel=warn msg=Downgrade added=true\nlevel=error msg=Failed added=true\n", "output")
+			util.AssertEqual(t, strings.Join(written, ","), "Kept:true,Downgrade:true,Failed:true", "after hooks")
+			util.AssertEqual(t, strings.Join(errorsSeen, ","), "Failed", "level filtered hook")
+		})
+	}
+}
+
+// Loggers made by RegisterLogger that run hooks themselves
+type hookingLogger struct {
+	logging.Logger
+}
+
+func (h hookingLogger) WithHooks(hooks []logging.Hook) logging.Logger {
+	return hookingLogger{Logger: h.Logger.(logging.HookLogger).WithHooks(hooks)}
+}
+
+func TestHooksWithSinks(t *testing.T) {
+	output := new(bytes.Buffer)
+	writer := new(minimalWriter)
+	util.AssertNoError(t, logging.RegisterEntryWriter("HookedEntryWriter", func(_ *logging.TLMLoggingInitialization) (logging.EntryWriter, error) {
+		return writer, nil
+	}), "register writer")
+	defer logging.UnregisterLogger("HookedEntryWriter")
+	util.AssertNoError(t, logging.RegisterLogger("HookingLogger", func(args *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		logger, err := logging.InitLogrus(args)
+		return hookingLogger{Logger: logger}, err
+	}), "register logger")
+	defer logging.UnregisterLogger("HookingLogger")
+
+	writerEntries := func() string {
+		written := make([]string, 0, len(writer.entries))
+		for _, entry := range writer.entries {
+			written = append(written, fmt.Sprintf("%s hooked=%v\n", entry.Message, entry.Fields["hooked"]))
+		}
+		return strings.Join(written, "")
+	}
+	logfmt := logging.Formatter{Type: logging.LogfmtFormat, TimeKey: "-"}
+	tests := []struct {
+		name       string
+		logType    logging.LogType
+		customType string
+		formatter  logging.Formatter
+		primary    func() string
+		expected   string
+	}{
+		{name: "Logrus", logType: logging.LogrusLogType, formatter: logfmt, primary: output.String, expected: "level=info msg=SHOUT hooked=true\n"},
+		{name: "EntryWriter", logType: logging.CustomLogType, customType: "HookedEntryWriter", primary: writerEntries, expected: "SHOUT hooked=true\n"},
+		// Only the fields set by the hooks reach loggers that can't be given their result
+		{name: "HookLogger", logType: logging.CustomLogType, customType: "HookingLogger", formatter: logfmt, primary: output.String, expected: "level=info msg=shout hooked=true\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output.Reset()
+			writer.entries = nil
+			beforeCount := 0
+			afterCount := 0
+			collector := logging.NewDebugLogCollector()
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{
+				Type:        test.logType,
+				CustomeType: test.customType,
+				Output:      output,
+				Formatter:   test.formatter,
+				Sinks:       []logging.Sink{collector},
+				Hooks: []logging.Hook{
+					logging.HookFuncs{
+						BeforeFunc: func(entry *logging.Entry) bool {
+							beforeCount++
+							entry.Message = strings.ToUpper(entry.Message)
+							entry.Fields["hooked"] = true
+							return entry.Message != "DROP"
+						},
+						AfterFunc: func(entry *logging.Entry) { afterCount++ },
+					},
+				},
+			}
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			tlm.Log(ctx).Info("drop")
+			tlm.Log(ctx).Info("shout")
+
+			util.AssertEqual(t, collector.GetNumberLogs(), 1, "count")
+			util.AssertEqual(t, collector.GetMessage(0), "SHOUT", "sinks see changes")
+			util.AssertEqual(t, test.primary(), test.expected, "primary")
+			util.AssertEqual(t, beforeCount, 2, "before runs once")
+			util.AssertEqual(t, afterCount, 1, "after runs once")
+		})
+	}
+}
+
+func TestHooksLogrusOutput(t *testing.T) {
+	first := new(bytes.Buffer)
+	logger, err := logging.InitLogrus(&logging.TLMLoggingInitialization{
+		Output:    first,
+		Formatter: logging.Formatter{Type: logging.LogfmtFormat, TimeKey: "-"},
+	})
+	util.AssertNoError(t, err, "init")
+	afterCount := 0
+	logger = logger.(logging.HookLogger).WithHooks([]logging.Hook{logging.HookFuncs{AfterFunc: func(entry *logging.Entry) { afterCount++ }}})
+
+	// Logrus keeps the output, so terminal detection and SetOutput still work
+	lrus := logger.(*logging.LogrusImpl).Logger
+	util.AssertEqual(t, lrus.Out, io.Writer(first), "output kept")
+	logger.Info("First")
+	second := new(bytes.Buffer)
+	lrus.SetOutput(second)
+	logger.Info("Second")
+
+	util.AssertEqual(t, first.String(), "level=info msg=First\n", "first output")
+	util.AssertEqual(t, second.String(), "level=info msg=Second\n", "second output")
+	util.AssertEqual(t, afterCount, 2, "after hooks")
+}
+
+func TestHooksFatal(t *testing.T) {
+	for _, logType := range []logging.LogType{logging.LogrusLogType, logging.SinkLogType} {
+		t.Run(logType.String(), func(t *testing.T) {
+			inits := new(tlm.TLMInitialization)
+			inits.Logging = &logging.TLMLoggingInitialization{
+				Type:   logType,
+				Output: io.Discard,
+				Hooks: []logging.Hook{
+					logging.HookFuncs{BeforeFunc: func(entry *logging.Entry) bool { return false }},
+				},
+			}
+			collector := logging.NewDebugLogCollector()
+			collector.SetupInitialization(inits.Logging)
+			exited := false
+			inits.Logging.ExitFunc = func(int) { exited = true }
+			ctx, err := tlm.Startup(inits)
+			util.AssertNoError(t, err, "startup")
+
+			// Dropped entries still panic and exit
+			util.AssertPanic(t, func() { tlm.Log(ctx).Panic("Dropped") }, "panic")
+			tlm.Log(ctx).Fatal("Dropped")
+			util.AssertEqual(t, exited, true, "exited")
+			util.AssertEqual(t, collector.GetNumberLogs(), 0, "count")
+		})
+	}
+}
+
+func TestHooksNotSupported(t *testing.T) {
+	type hooklessLogger struct {
+		logging.Logger
+	}
+	util.AssertNoError(t, logging.RegisterLogger("HooklessLogger", func(_ *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		return hooklessLogger{Logger: &logging.NullLogger}, nil
+	}), "register")
+	defer logging.UnregisterLogger("HooklessLogger")
+
+	_, err := logging.InitLogging(&logging.TLMLoggingInitialization{
+		Type:        logging.CustomLogType,
+		CustomeType: "HooklessLogger",
+		Hooks:       []logging.Hook{logging.HookFuncs{}},
+	})
+	util.AssertError(t, err, "hooks")
+}
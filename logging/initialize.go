@@ -3,6 +3,7 @@ package logging
 import (
 	"errors"
 	"fmt"
+	"sync"
 )
 
 func InitLogging(args *TLMLoggingInitialization) (TLMLogger, error) {
@@ -23,8 +24,8 @@ func InitLogging(args *TLMLoggingInitialization) (TLMLogger, error) {
 		if len(args.CustomeType) == 0 {
 			return nil, errors.New("type 'Custom' requires 'CustomeType' to be set")
 		}
-		if customLoggerInit, ok := registeredLoggers[args.CustomeType]; ok {
-			log, err = customLoggerInit(args)
+		if registration, ok := lookupLogger(args.CustomeType); ok {
+			log, err = registration.init(args)
 		} else {
 			err = fmt.Errorf("custom type could is not registered: %s", args.CustomeType)
 		}
@@ -33,34 +34,126 @@ func InitLogging(args *TLMLoggingInitialization) (TLMLogger, error) {
 		}
 	case LogrusLogType:
 		log, err = InitLogrus(args)
+	case SinkLogType:
+		log, err = initSinkLogger(args)
 	default:
 		return nil, fmt.Errorf("unknown logging type: %v", args.Type)
 	}
 	if err != nil {
 		return nil, err
 	}
+	tee := args.Type != SinkLogType && len(args.Sinks) > 0
+	if len(args.Hooks) > 0 {
+		hookLogger, ok := log.(HookLogger)
+		if !ok {
+			return nil, fmt.Errorf("logger does not support 'Hooks': %v", args.Type)
+		}
+		hooks := args.Hooks
+		if _, ok := log.(hookResultLogger); tee && !ok {
+			// The Before hooks run for the sinks, and the logger is given the result
+			hooks = afterHooks(hooks)
+		}
+		log = hookLogger.WithHooks(hooks)
+	}
+	if args.ExitFunc != nil {
+		if exitLogger, ok := log.(ExitFuncLogger); ok {
+			log = exitLogger.WithExitFunc(args.ExitFunc)
+		} else {
+			log = &exitFuncPolyfill{Logger: log, exitFunc: args.ExitFunc}
+		}
+	}
+	if tee {
+		sinks := newEntryLogger(args, false)
+		// The Before hooks run once for both the sinks and the primary logger, and After only runs for the primary logger
+		sinks.settings.hooks = args.Hooks
+		log = &teeLogger{
+			primary: log,
+			sinks:   sinks,
+		}
+	}
 
 	return &selfReferentialLogger{
 		LoggerImpl: log,
+		settings:   newLoggerSettings(args),
 	}, nil
 }
 
-var registeredLoggers = make(map[string]CustomeLoggerInitializationFunc)
+// Describes a registered logger
+type LoggerInfo struct {
+	// The name used for CustomeType
+	Name        string
+	Description string
+	// Formatter types the logger supports. Empty if not known
+	Formatters []FormatterType
+	// Names of the TLMLoggingInitialization fields the logger supports, such as "Output" or "ExitFunc". Empty if not known
+	Options []string
+}
+
+type loggerRegistration struct {
+	info LoggerInfo
+	init CustomeLoggerInitializationFunc
+}
+
+var (
+	registeredLoggersLock sync.RWMutex
+	registeredLoggers     = make(map[string]loggerRegistration)
+)
+
+func lookupLogger(typeName string) (loggerRegistration, bool) {
+	registeredLoggersLock.RLock()
+	defer registeredLoggersLock.RUnlock()
+	registration, ok := registeredLoggers[typeName]
+	return registration, ok
+}
 
 func RegisterLogger(typeName string, loggerInit CustomeLoggerInitializationFunc) error {
-	if len(typeName) == 0 {
+	return RegisterLoggerWithInfo(LoggerInfo{Name: typeName}, loggerInit)
+}
+
+// Register a logger along with a description of it, which is listed by RegisteredLoggers
+func RegisterLoggerWithInfo(info LoggerInfo, loggerInit CustomeLoggerInitializationFunc) error {
+	if len(info.Name) == 0 {
 		return errors.New("typeName must be set")
 	}
 	if loggerInit == nil {
 		return errors.New("loggerInit cannot be nil")
 	}
-	if _, ok := registeredLoggers[typeName]; ok {
-		return fmt.Errorf("logger of type '%s' already registered", typeName)
+
+	registeredLoggersLock.Lock()
+	defer registeredLoggersLock.Unlock()
+	if _, ok := registeredLoggers[info.Name]; ok {
+		return fmt.Errorf("logger of type '%s' already registered", info.Name)
 	}
-	registeredLoggers[typeName] = loggerInit
+	info.Formatters = append([]FormatterType(nil), info.Formatters...)
+	info.Options = append([]string(nil), info.Options...)
+	registeredLoggers[info.Name] = loggerRegistration{info: info, init: loggerInit}
 	return nil
 }
 
+// Register a logger from an init function. Panics if it can't be registered
+func MustRegisterLogger(info LoggerInfo, loggerInit CustomeLoggerInitializationFunc) {
+	if err := RegisterLoggerWithInfo(info, loggerInit); err != nil {
+		panic(err)
+	}
+}
+
 func UnregisterLogger(typeName string) {
+	registeredLoggersLock.Lock()
+	defer registeredLoggersLock.Unlock()
 	delete(registeredLoggers, typeName)
 }
+
+// Get the registered loggers, sorted by name
+func RegisteredLoggers() []LoggerInfo {
+	registeredLoggersLock.RLock()
+	defer registeredLoggersLock.RUnlock()
+
+	loggers := make([]LoggerInfo, 0, len(registeredLoggers))
+	for _, name := range sortedFieldKeys(registeredLoggers) {
+		info := registeredLoggers[name].info
+		info.Formatters = append([]FormatterType(nil), info.Formatters...)
+		info.Options = append([]string(nil), info.Options...)
+		loggers = append(loggers, info)
+	}
+	return loggers
+}
@@ -2,6 +2,8 @@ package logging_test
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/rcmaniac25/tlm/logging"
@@ -100,6 +102,31 @@ func TestInitLogging(t *testing.T) {
 				expectLogger: true,
 			},
 		},
+		{
+			name: "Custom type (options)",
+			args: args{
+				logArgs: &logging.TLMLoggingInitialization{
+					Type:           logging.CustomLogType,
+					CustomeType:    "MyFakeLogger",
+					BackendOptions: map[string]any{"MyFakeLogger": "enabled"},
+				},
+				setup: func() {
+					logging.RegisterLogger("MyFakeLogger", func(args *logging.TLMLoggingInitialization) (logging.Logger, error) {
+						if option, ok := logging.GetBackendOptions[string](args, "MyFakeLogger"); !ok || option != "enabled" {
+							return nil, errors.New("missing options")
+						}
+						return &logging.NullLogger, nil
+					})
+				},
+				cleanup: func() {
+					logging.UnregisterLogger("MyFakeLogger")
+				},
+			},
+			expected: expected{
+				expectError:  false,
+				expectLogger: true,
+			},
+		},
 		{
 			name: "Custom type (failed)",
 			args: args{
@@ -302,3 +329,88 @@ func TestUnregisterLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisteredLoggers(t *testing.T) {
+	nullInit := func(_ *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		return &logging.NullLogger, nil
+	}
+	util.AssertNoError(t, logging.RegisterLoggerWithInfo(logging.LoggerInfo{
+		Name:        "zInfoLogger",
+		Description: "Logs nothing",
+		Formatters:  []logging.FormatterType{logging.JsonFormat},
+		Options:     []string{"ExitFunc"},
+	}, nullInit), "register with info")
+	defer logging.UnregisterLogger("zInfoLogger")
+	util.AssertNoError(t, logging.RegisterLogger("aPlainLogger", nullInit), "register")
+	defer logging.UnregisterLogger("aPlainLogger")
+
+	// Other tests may have registered loggers of their own, so only look at ours
+	find := func(loggers []logging.LoggerInfo, name string) int {
+		for i, info := range loggers {
+			if info.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	loggers := logging.RegisteredLoggers()
+	plain := find(loggers, "aPlainLogger")
+	info := find(loggers, "zInfoLogger")
+	if plain < 0 || info < 0 {
+		t.Fatalf("registered loggers missing: %v", loggers)
+	}
+	util.AssertEqual(t, plain < info, true, "sorted")
+	util.AssertEqual(t, loggers[plain].Description, "", "no description")
+	util.AssertEqual(t, loggers[info].Description, "Logs nothing", "description")
+	util.AssertEqual(t, fmt.Sprint(loggers[info].Formatters), "[json]", "formatters")
+	util.AssertEqual(t, fmt.Sprint(loggers[info].Options), "[ExitFunc]", "options")
+
+	// The listing is a copy
+	loggers[info].Options[0] = "Output"
+	loggers = logging.RegisteredLoggers()
+	util.AssertEqual(t, loggers[find(loggers, "zInfoLogger")].Options[0], "ExitFunc", "copied options")
+
+	logging.UnregisterLogger("aPlainLogger")
+	loggers = logging.RegisteredLoggers()
+	util.AssertEqual(t, find(loggers, "aPlainLogger"), -1, "unregistered")
+	util.AssertNotEqual(t, find(loggers, "zInfoLogger"), -1, "still registered")
+}
+
+func TestMustRegisterLogger(t *testing.T) {
+	info := logging.LoggerInfo{Name: "mustLogger"}
+	nullInit := func(_ *logging.TLMLoggingInitialization) (logging.Logger, error) {
+		return &logging.NullLogger, nil
+	}
+	defer logging.UnregisterLogger("mustLogger")
+
+	util.AssertNoPanic(t, func() { logging.MustRegisterLogger(info, nullInit) }, "register")
+	util.AssertPanic(t, func() { logging.MustRegisterLogger(info, nullInit) }, "already registered")
+	util.AssertPanic(t, func() { logging.MustRegisterLogger(logging.LoggerInfo{}, nullInit) }, "no name")
+}
+
+func TestRegisterLoggerConcurrent(t *testing.T) {
+	var wait sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			name := fmt.Sprintf("concurrentLogger%d", i)
+			for j := 0; j < 50; j++ {
+				err := logging.RegisterLogger(name, func(_ *logging.TLMLoggingInitialization) (logging.Logger, error) {
+					return &logging.NullLogger, nil
+				})
+				if err != nil {
+					t.Errorf("register %s: %v", name, err)
+					return
+				}
+				logging.RegisteredLoggers()
+				_, err = logging.InitLogging(&logging.TLMLoggingInitialization{Type: logging.CustomLogType, CustomeType: name})
+				if err != nil {
+					t.Errorf("init %s: %v", name, err)
+				}
+				logging.UnregisterLogger(name)
+			}
+		}(i)
+	}
+	wait.Wait()
+}
@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+	journalMaxFieldNameLength = 64
+)
+
+type JournaldOptions struct {
+	// Default is DefaultJournaldSocket
+	SocketPath string
+	// Default is the name of the program
+	SyslogIdentifier string
+}
+
+func (o *JournaldOptions) setDefaults() {
+	if o.SocketPath == "" {
+		o.SocketPath = DefaultJournaldSocket
+	}
+	if o.SyslogIdentifier == "" {
+		o.SyslogIdentifier = filepath.Base(os.Args[0])
+	}
+}
+
+// Journal field names are upper case letters, numbers, and underscores. They can't start with an underscore or number
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "FIELD_" + name
+	}
+	if len(name) > journalMaxFieldNameLength {
+		name = name[:journalMaxFieldNameLength]
+	}
+	return name
+}
+
+func journalFieldValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+func writeJournalField(b *bytes.Buffer, name, value string) {
+	if strings.IndexByte(value, '\n') < 0 {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+
+	// Values with new lines are written with their length
+	b.WriteString(name)
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// Encode an entry with the native journal protocol
+func encodeJournalEntry(entry *Entry, identifier string) []byte {
+	var b bytes.Buffer
+	used := map[string]bool{"MESSAGE": true, "PRIORITY": true, "SYSLOG_IDENTIFIER": true}
+
+	writeJournalField(&b, "MESSAGE", entry.Message)
+	writeJournalField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(entry.Level)))
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", identifier)
+	if entry.Caller != nil {
+		writeJournalField(&b, "CODE_FILE", entry.Caller.File)
+		writeJournalField(&b, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		writeJournalField(&b, "CODE_FUNC", entry.Caller.Function)
+		used["CODE_FILE"], used["CODE_LINE"], used["CODE_FUNC"] = true, true, true
+	}
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := journalFieldName(key)
+		if used[name] {
+			name = journalFieldName("FIELDS_" + name)
+		}
+		writeJournalField(&b, name, journalFieldValue(entry.Fields[key]))
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package logging
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// Writes entries to the systemd journal with the native protocol. Fields are written as upper cased journal fields
+type JournaldSink struct {
+	options JournaldOptions
+
+	lock sync.Mutex
+	conn *net.UnixConn
+}
+
+func NewJournaldSink(options JournaldOptions) (*JournaldSink, error) {
+	options.setDefaults()
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: options.SocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldSink{
+		options: options,
+		conn:    conn,
+	}, nil
+}
+
+func (j *JournaldSink) Write(entry *Entry) error {
+	data := encodeJournalEntry(entry, j.options.SyslogIdentifier)
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	_, err := j.conn.Write(data)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, unix.EMSGSIZE) && !errors.Is(err, unix.ENOBUFS) {
+		return err
+	}
+	return j.writeMemfd(data)
+}
+
+// Entries too large for a datagram are written to a sealed memfd, which is passed to journald instead
+func (j *JournaldSink) writeMemfd(data []byte) error {
+	fd, err := unix.MemfdCreate("tlm-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "tlm-journal")
+	defer file.Close()
+
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+	if _, err = unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return err
+	}
+
+	// WriteMsgUnix doesn't allow connected datagram sockets, so send the message directly
+	raw, err := j.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := unix.UnixRights(int(file.Fd()))
+	if writeErr := raw.Write(func(socket uintptr) bool {
+		err = unix.Sendmsg(int(socket), nil, rights, nil, 0)
+		return err != unix.EAGAIN
+	}); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+func (j *JournaldSink) Close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.conn.Close()
+}
@@ -0,0 +1,137 @@
+//go:build linux
+
+package logging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Stand-in for journald, listening on a datagram socket
+func listenJournal(t *testing.T) (*net.UnixConn, string, func()) {
+	dir, err := os.MkdirTemp("", "tlm")
+	util.AssertNoError(t, err, "temp dir")
+
+	address := filepath.Join(dir, "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+	util.AssertNoError(t, err, "listen")
+	return conn, address, func() {
+		conn.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// Read an entry, either from the datagram or the memfd passed with it
+func readJournalEntry(t *testing.T, conn *net.UnixConn) []byte {
+	data := make([]byte, 65536)
+	oob := make([]byte, 1024)
+	util.AssertNoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)), "deadline")
+	n, oobn, _, _, err := conn.ReadMsgUnix(data, oob)
+	util.AssertNoError(t, err, "read")
+	if oobn == 0 {
+		return data[:n]
+	}
+
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	util.AssertNoError(t, err, "control message")
+	util.AssertEqual(t, len(messages), 1, "control message count")
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	util.AssertNoError(t, err, "rights")
+	util.AssertEqual(t, len(fds), 1, "fd count")
+
+	file := os.NewFile(uintptr(fds[0]), "journal")
+	defer file.Close()
+	_, err = file.Seek(0, io.SeekStart)
+	util.AssertNoError(t, err, "seek")
+	content, err := io.ReadAll(file)
+	util.AssertNoError(t, err, "read memfd")
+	return content
+}
+
+// Parse the native journal protocol
+func parseJournalEntry(t *testing.T, data []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		util.AssertNotEqual(t, end, -1, "field end")
+		line := string(data[:end])
+		data = data[end+1:]
+
+		if idx := strings.IndexByte(line, '='); idx >= 0 {
+			fields[line[:idx]] = line[idx+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[:8])
+		fields[line] = string(data[8 : 8+size])
+		util.AssertEqual(t, data[8+size], byte('\n'), "binary field end")
+		data = data[9+size:]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	conn, address, cleanup := listenJournal(t)
+	defer cleanup()
+
+	sink, err := logging.NewJournaldSink(logging.JournaldOptions{SocketPath: address, SyslogIdentifier: "tester"})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	logger := startSinkLogger(t, sink, func(args *logging.TLMLoggingInitialization) {
+		args.Formatter.FunctionKey = "~"
+	})
+	logger.WithFields(util.Fields{
+		"myField":    "value",
+		"multi-line": "first\nsecond",
+		"_trusted":   true,
+		"1st":        1,
+		"message":    "clash",
+	}).Warn("Hello journal")
+
+	fields := parseJournalEntry(t, readJournalEntry(t, conn))
+	util.AssertEqual(t, fields["MESSAGE"], "Hello journal", "message")
+	util.AssertEqual(t, fields["PRIORITY"], "4", "priority")
+	util.AssertEqual(t, fields["SYSLOG_IDENTIFIER"], "tester", "identifier")
+	util.AssertEqual(t, fields["CODE_FUNC"], "github.com/rcmaniac25/tlm/logging_test.TestJournald", "function")
+	util.AssertContains(t, fields["CODE_FILE"], "journald_linux_test.go", "file")
+	util.AssertNotEqual(t, fields["CODE_LINE"], "", "line")
+	util.AssertEqual(t, fields["MYFIELD"], "value", "field")
+	util.AssertEqual(t, fields["MULTI_LINE"], "first\nsecond", "multi-line field")
+	util.AssertEqual(t, fields["TRUSTED"], "true", "trusted field")
+	util.AssertEqual(t, fields["FIELD_1ST"], "1", "number field")
+	util.AssertEqual(t, fields["FIELDS_MESSAGE"], "clash", "clashing field")
+}
+
+func TestJournaldLargeEntry(t *testing.T) {
+	conn, address, cleanup := listenJournal(t)
+	defer cleanup()
+
+	sink, err := logging.NewJournaldSink(logging.JournaldOptions{SocketPath: address})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	// Larger then any datagram can be
+	message := strings.Repeat("large", 1024*1024)
+	util.AssertNoError(t, sink.Write(&logging.Entry{Level: logging.ErrorLevel, Message: message}), "write")
+
+	fields := parseJournalEntry(t, readJournalEntry(t, conn))
+	util.AssertEqual(t, fields["MESSAGE"] == message, true, "message")
+	util.AssertEqual(t, fields["PRIORITY"], "3", "priority")
+	util.AssertEqual(t, fields["SYSLOG_IDENTIFIER"], filepath.Base(os.Args[0]), "identifier")
+}
+
+func TestJournaldNoSocket(t *testing.T) {
+	_, err := logging.NewJournaldSink(logging.JournaldOptions{SocketPath: filepath.Join(os.TempDir(), "tlm-missing.sock")})
+	util.AssertError(t, err, "missing socket")
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package logging
+
+import (
+	"errors"
+)
+
+// The systemd journal is only available on Linux
+type JournaldSink struct{}
+
+func NewJournaldSink(options JournaldOptions) (*JournaldSink, error) {
+	return nil, errors.New("journald is only supported on linux")
+}
+
+func (j *JournaldSink) Write(entry *Entry) error {
+	return errors.New("journald is only supported on linux")
+}
+
+func (j *JournaldSink) Close() error {
+	return nil
+}
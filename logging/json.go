@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Formats entries as JSON with the same keys as logrus' JSON formatter. Unlike logrus, the field order can be set, fields
+// can be put under a data key, and nested fields can be flattened. Time, level, message, and caller info come first.
+type jsonFormatter struct {
+	formatter Formatter
+}
+
+func newJsonFormatter(formatter Formatter) *jsonFormatter {
+	return &jsonFormatter{
+		formatter: formatter,
+	}
+}
+
+func (j *jsonFormatter) Format(entry *Entry) ([]byte, error) {
+	var b bytes.Buffer
+
+	usedKeys := make(map[string]bool)
+	w := &jsonObjectWriter{b: &b}
+	write := func(key string, value any) {
+		w.field(key, value)
+		usedKeys[key] = true
+	}
+
+	w.begin()
+	if key, ok := j.formatter.timeKey(); ok {
+		write(key, entry.Time.Format(j.formatter.timeFormat()))
+	}
+	if key, ok := j.formatter.levelKey(); ok {
+		write(key, entry.Level.String())
+	}
+	write(j.formatter.messageKey(), entry.Message)
+	if entry.Caller != nil {
+		for _, field := range j.formatter.callerFields(entry.Caller) {
+			write(field.key, field.value)
+		}
+	}
+
+	fields := j.formatter.layoutFields(entry)
+	if j.formatter.DataKey != "" {
+		if len(fields) > 0 {
+			var data bytes.Buffer
+			dataWriter := &jsonObjectWriter{b: &data}
+			dataWriter.begin()
+			for _, field := range fields {
+				dataWriter.field(field.key, field.value)
+			}
+			dataWriter.end()
+			w.field(j.formatter.DataKey, json.RawMessage(data.Bytes()))
+		}
+	} else {
+		for _, field := range fields {
+			fieldKey := field.key
+			if usedKeys[fieldKey] {
+				// Same as logrus, don't let fields clobber the entry values
+				fieldKey = "fields." + fieldKey
+			}
+			w.field(fieldKey, field.value)
+		}
+	}
+	w.end()
+
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Formats entries as strict logfmt: time, level, and message first. Then caller info, and finally fields, sorted by key unless
+// the formatter sets a field order.
+type logfmtFormatter struct {
+	formatter Formatter
+}
+
+func newLogfmtFormatter(formatter Formatter) *logfmtFormatter {
+	return &logfmtFormatter{
+		formatter: formatter,
+	}
+}
+
+func (l *logfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	var b bytes.Buffer
+
+	usedKeys := make(map[string]bool)
+	write := func(key string, value any) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		writeLogfmtKey(&b, key)
+		b.WriteByte('=')
+		writeLogfmtValue(&b, value, l.formatter.timeFormat())
+		usedKeys[key] = true
+	}
+
+	if key, ok := l.formatter.timeKey(); ok {
+		write(key, entry.Time.Format(l.formatter.timeFormat()))
+	}
+	if key, ok := l.formatter.levelKey(); ok {
+		write(key, entry.Level.String())
+	}
+	write(l.formatter.messageKey(), entry.Message)
+	if entry.Caller != nil {
+		for _, field := range l.formatter.callerFields(entry.Caller) {
+			write(field.key, field.value)
+		}
+	}
+
+	prefix := ""
+	if l.formatter.DataKey != "" {
+		separator := l.formatter.FlattenSeparator
+		if separator == "" {
+			separator = "."
+		}
+		prefix = l.formatter.DataKey + separator
+	}
+	for _, field := range l.formatter.layoutFields(entry) {
+		fieldKey := prefix + field.key
+		if usedKeys[fieldKey] {
+			// Same as logrus, don't let fields clobber the entry values
+			fieldKey = "fields." + fieldKey
+		}
+		write(fieldKey, field.value)
+	}
+
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func invalidLogfmtRune(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError
+}
+
+func writeLogfmtKey(b *bytes.Buffer, key string) {
+	if key == "" {
+		b.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if invalidLogfmtRune(r) {
+			b.WriteByte('_')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+}
+
+func writeLogfmtValue(b *bytes.Buffer, value any, timeFormat string) {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		str = "null"
+	case string:
+		str = v
+	case error:
+		str = v.Error()
+	case time.Time:
+		str = v.Format(timeFormat)
+	default:
+		str = fmt.Sprint(v)
+	}
+
+	if str != "" && strings.IndexFunc(str, invalidLogfmtRune) < 0 && strings.IndexByte(str, '\\') < 0 {
+		b.WriteString(str)
+		return
+	}
+
+	b.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString("\\n")
+		case '\r':
+			b.WriteString("\\r")
+		case '\t':
+			b.WriteString("\\t")
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(b, "\\u%04x", r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
@@ -0,0 +1,191 @@
+package logging_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// A strict logfmt parser, used to make sure the output can be read back
+func parseLogfmt(t *testing.T, line string) ([]string, map[string]string) {
+	keys := make([]string, 0)
+	values := make(map[string]string)
+
+	line = strings.TrimSuffix(line, "\n")
+	for len(line) > 0 {
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			t.Fatalf("Invalid logfmt key: %s", line)
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+
+		var value strings.Builder
+		if strings.HasPrefix(line, "\"") {
+			i := 1
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] != '\\' {
+					value.WriteByte(line[i])
+					continue
+				}
+				i++
+				switch line[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 'r':
+					value.WriteByte('\r')
+				case 't':
+					value.WriteByte('\t')
+				case 'u':
+					value.WriteByte(byte(strings.IndexByte("0123456789abcdef", line[i+3])*16 + strings.IndexByte("0123456789abcdef", line[i+4])))
+					i += 4
+				default:
+					value.WriteByte(line[i])
+				}
+			}
+			if i >= len(line) {
+				t.Fatalf("Unterminated logfmt value: %s", line)
+			}
+			line = line[i+1:]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value.WriteString(line[:end])
+			line = line[end:]
+		}
+		if strings.ContainsAny(key, " \"=") {
+			t.Fatalf("Invalid logfmt key: %s", key)
+		}
+		keys = append(keys, key)
+		values[key] = value.String()
+
+		if len(line) > 0 {
+			if line[0] != ' ' {
+				t.Fatalf("Expected a space between values: %s", line)
+			}
+			line = line[1:]
+		}
+	}
+	return keys, values
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter logging.Formatter
+		logFunc   func(logging.Logger)
+		expected  string
+	}{
+		{
+			name:      "Default",
+			formatter: logging.Formatter{TimeKey: "-"},
+			logFunc:   func(logger logging.Logger) { logger.Info("Hello World") },
+			expected:  "level=info msg=\"Hello World\"\n",
+		},
+		{
+			name:      "Field Order",
+			formatter: logging.Formatter{TimeKey: "-"},
+			logFunc: func(logger logging.Logger) {
+				logger.WithField("zebra", 1).WithField("apple", true).WithField("mango", 1.5).Warn("Fruit")
+			},
+			expected: "level=warn msg=Fruit apple=true mango=1.5 zebra=1\n",
+		},
+		{
+			name:      "Quoting",
+			formatter: logging.Formatter{TimeKey: "-"},
+			logFunc: func(logger logging.Logger) {
+				logger.WithFields(util.Fields{
+					"quote":   "say \"hi\"",
+					"newline": "line1\nline2",
+					"equals":  "a=b",
+					"empty":   "",
+					"slash":   "C:\\path",
+					"control": "bell\a",
+					"nil":     nil,
+					"err":     errors.New("bad thing"),
+				}).Error("Quotes")
+			},
+			expected: "level=error msg=Quotes control=\"bell\\u0007\" empty=\"\" equals=\"a=b\" err=\"bad thing\" newline=\"line1\\nline2\" nil=null quote=\"say \\\"hi\\\"\" slash=\"C:\\\\path\"\n",
+		},
+		{
+			name:      "Invalid Keys",
+			formatter: logging.Formatter{TimeKey: "-"},
+			logFunc:   func(logger logging.Logger) { logger.WithField("my key=\"x\"", "value").Info("Keys") },
+			expected:  "level=info msg=Keys my_key__x_=value\n",
+		},
+		{
+			name:      "Clashing Fields",
+			formatter: logging.Formatter{TimeKey: "-"},
+			logFunc:   func(logger logging.Logger) { logger.WithField("msg", "field").WithField("level", 1).Info("Message") },
+			expected:  "level=info msg=Message fields.level=1 fields.msg=field\n",
+		},
+		{
+			name:      "Key Mapping",
+			formatter: logging.Formatter{TimeKey: "-", MessageKey: "~", LevelKey: "severity"},
+			logFunc:   func(logger logging.Logger) { logger.Debug("Mapped") },
+			expected:  "severity=debug message=Mapped\n",
+		},
+		{
+			name:      "Skip Level",
+			formatter: logging.Formatter{TimeKey: "-", LevelKey: "-"},
+			logFunc:   func(logger logging.Logger) { logger.Info("No level") },
+			expected:  "msg=\"No level\"\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logArgs := new(logging.TLMLoggingInitialization)
+			logArgs.Level = logging.DebugLevel
+			logArgs.Formatter = test.formatter
+			logArgs.Formatter.Type = logging.LogfmtFormat
+			logger, buffer := createLogger(logArgs)
+
+			test.logFunc(logger)
+			util.AssertEqual(t, buffer.String(), test.expected, "output")
+
+			// Make sure it's parsable
+			parseLogfmt(t, buffer.String())
+		})
+	}
+}
+
+func TestLogfmtTime(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Formatter = logging.Formatter{
+		Type:       logging.LogfmtFormat,
+		TimeKey:    "ts",
+		TimeFormat: time.RFC3339Nano,
+	}
+	logger, buffer := createLogger(logArgs)
+
+	logTime := time.Now()
+	logger.WithField("when", logTime).Info("Time")
+
+	keys, values := parseLogfmt(t, buffer.String())
+	util.AssertEqual(t, strings.Join(keys, ","), "ts,level,msg,when", "keys")
+	util.AssertEqual(t, values["when"], logTime.Format(time.RFC3339Nano), "time field")
+	AssertTime(t, "ts", time.RFC3339Nano, values["ts"], buffer.String(), logTime)
+}
+
+func TestLogfmtCaller(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Formatter = logging.Formatter{
+		Type:        logging.LogfmtFormat,
+		TimeKey:     "-",
+		FunctionKey: "~",
+	}
+	logger, buffer := createLogger(logArgs)
+
+	logger.WithField("myField", "value").Info("Caller")
+
+	keys, values := parseLogfmt(t, buffer.String())
+	util.AssertEqual(t, strings.Join(keys, ","), "level,msg,function,file,myField", "keys")
+	util.AssertEqual(t, values["function"], "github.com/rcmaniac25/tlm/logging_test.TestLogfmtCaller", "function")
+	util.AssertContains(t, values["file"], "logfmt_test.go:", "file")
+}
@@ -1,9 +1,13 @@
 package logging
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"io"
+	"os"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/rcmaniac25/tlm/util"
 
@@ -14,43 +18,107 @@ const (
 	LoggingPackageName = "github.com/rcmaniac25/tlm/logging"
 	LogrusPackageName  = "github.com/sirupsen/logrus"
 
-	callerMinFrameSkip = 6
-
 	// From logrus as no better amount has really been identified
 	maxFrameCount = 25
 )
 
 type LogrusImpl struct {
-	Log   *logrus.Logger
-	Entry *logrus.Entry
+	// Named Logger instead of Log as Log is part of the Logger interface
+	Logger    *logrus.Logger
+	Entry     *logrus.Entry
+	Verbosity int
+
+	// Used by WithError. Logrus' own error key is used when not set
+	errorKey          string
+	captureErrorStack bool
+	// Record the order fields are added in, only needed by InsertionFieldOrder
+	fieldOrder bool
+}
+
+// Logrus specific options. Set with TLMLoggingInitialization.SetBackendOptions(LogrusLogType.String(), LogrusOptions{...})
+type LogrusOptions struct {
+	// Only work with logrus. TLMLoggingInitialization.Hooks work with any logger
+	Hooks []logrus.Hook
+	// Report the caller even if Formatter.FunctionKey isn't set
+	ReportCaller bool
+
+	// Only used by the text formatter
+	DisableColors bool
+	ForceColors   bool
+	FullTimestamp bool
+	PadLevelText  bool
+	DisableQuote  bool
+
+	// Only used by the JSON formatter
+	PrettyPrint bool
+	DataKey     string
 }
 
 func InitLogrus(args *TLMLoggingInitialization) (Logger, error) {
 	logger := &LogrusImpl{
-		Log: logrus.New(),
+		Logger:            logrus.New(),
+		Verbosity:         args.Verbosity,
+		errorKey:          args.Formatter.errorKey(),
+		captureErrorStack: args.CaptureErrorStack,
+		fieldOrder:        args.Formatter.FieldOrder == InsertionFieldOrder,
 	}
 
 	if args.Output != nil {
-		logger.Log.SetOutput(args.Output)
+		logger.Logger.SetOutput(args.Output)
 	}
 
 	if level, ok := convertLogLevel(args.Level); ok {
-		logger.Log.SetLevel(level)
+		logger.Logger.SetLevel(level)
 	}
 
-	if formatter, ok := getFormatter(args.Formatter, logger.Log.Formatter); ok {
-		logger.Log.Formatter = formatter
+	formatter, ok, err := getFormatter(args.Formatter, logger.Logger.Formatter, logger.Logger.Out, args.clock())
+	if err != nil {
+		return nil, err
 	}
-	if args.Formatter.FunctionKey != "" && args.Formatter.FunctionKey != "-" {
-		logger.Log.SetReportCaller(true)
-		logger.Log.AddHook(logger)
+	if ok {
+		logger.Logger.Formatter = formatter
+	}
+	options, _, err := LookupBackendOptions[LogrusOptions](args, LogrusLogType.String())
+	if err != nil {
+		return nil, err
+	}
+	setFormatterOptions(options, logger.Logger.Formatter)
+
+	if options.ReportCaller || args.Formatter.reportCaller() {
+		logger.Logger.SetReportCaller(true)
+		logger.Logger.AddHook(logger)
+	}
+	if args.Clock != nil {
+		// Hooks run after logrus sets the time, so the clock's time replaces it
+		logger.Logger.AddHook(&clockHook{clock: args.Clock})
+	}
+	for _, hook := range options.Hooks {
+		logger.Logger.AddHook(hook)
 	}
 
 	return logger, nil
 }
 
+func setFormatterOptions(options LogrusOptions, formatter logrus.Formatter) {
+	switch form := formatter.(type) {
+	case *logrus.TextFormatter:
+		form.DisableColors = form.DisableColors || options.DisableColors
+		form.ForceColors = form.ForceColors || options.ForceColors
+		form.FullTimestamp = form.FullTimestamp || options.FullTimestamp
+		form.PadLevelText = form.PadLevelText || options.PadLevelText
+		form.DisableQuote = form.DisableQuote || options.DisableQuote
+	case *logrus.JSONFormatter:
+		form.PrettyPrint = form.PrettyPrint || options.PrettyPrint
+		if options.DataKey != "" {
+			form.DataKey = options.DataKey
+		}
+	}
+}
+
 func convertLogLevel(level LogLevel) (logrus.Level, bool) {
 	switch level {
+	case TraceLevel:
+		return logrus.TraceLevel, true
 	case DebugLevel:
 		return logrus.DebugLevel, true
 	case InfoLevel:
@@ -67,22 +135,82 @@ func convertLogLevel(level LogLevel) (logrus.Level, bool) {
 	return logrus.InfoLevel, false
 }
 
+func convertLogrusLevel(level logrus.Level) LogLevel {
+	switch level {
+	case logrus.TraceLevel:
+		return TraceLevel
+	case logrus.DebugLevel:
+		return DebugLevel
+	case logrus.InfoLevel:
+		return InfoLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	case logrus.ErrorLevel:
+		return ErrorLevel
+	case logrus.PanicLevel:
+		return PanicLevel
+	case logrus.FatalLevel:
+		return FatalLevel
+	}
+	return DefaultLevel
+}
+
 // Formatting
-func getFormatter(formatterArgs Formatter, def logrus.Formatter) (logrus.Formatter, bool) {
+
+// Allows TLM's own formatters to be used by logrus
+type logrusEntryFormatter struct {
+	formatter EntryFormatter
+}
+
+func (f *logrusEntryFormatter) Format(ent *logrus.Entry) ([]byte, error) {
+	return f.formatter.Format(entryFromLogrus(ent))
+}
+
+// The entry shares its fields with the logrus entry
+func entryFromLogrus(ent *logrus.Entry) *Entry {
+	entry := &Entry{
+		Time:    ent.Time,
+		Level:   convertLogrusLevel(ent.Level),
+		Message: ent.Message,
+		Fields:  util.Fields(ent.Data),
+	}
+	if ent.Context != nil {
+		entry.FieldOrder, _ = ent.Context.Value(fieldOrderContextKey{}).([]string)
+	}
+	if ent.HasCaller() {
+		entry.Caller = ent.Caller
+	}
+	return entry
+}
+
+func getFormatter(formatterArgs Formatter, def logrus.Formatter, output io.Writer, clock util.Clock) (logrus.Formatter, bool, error) {
 	switch formatterArgs.Type {
 	case TextFormat:
-		return getTextFormatter(formatterArgs, nil)
+		formatter, ok := getTextFormatter(formatterArgs, nil)
+		return formatter, ok, nil
 	case JsonFormat:
-		return getJsonFormatter(formatterArgs, nil)
+		if formatterArgs.customFieldLayout() {
+			return &logrusEntryFormatter{formatter: newJsonFormatter(formatterArgs)}, true, nil
+		}
+		formatter, ok := getJsonFormatter(formatterArgs, nil)
+		return formatter, ok, nil
+	case LogfmtFormat, EcsFormat, ConsoleFormat, CustomFormat:
+		formatter, err := formatterArgs.entryFormatter(output, clock)
+		if err != nil {
+			return nil, false, err
+		}
+		return &logrusEntryFormatter{formatter: formatter}, true, nil
 	case DefaultFormat:
 		if text, ok := def.(*logrus.TextFormatter); ok {
-			return getTextFormatter(formatterArgs, text)
+			formatter, ok := getTextFormatter(formatterArgs, text)
+			return formatter, ok, nil
 		}
 		if json, ok := def.(*logrus.JSONFormatter); ok {
-			return getJsonFormatter(formatterArgs, json)
+			formatter, ok := getJsonFormatter(formatterArgs, json)
+			return formatter, ok, nil
 		}
 	}
-	return nil, false
+	return nil, false, nil
 }
 
 func getTextFormatter(formatterArgs Formatter, form *logrus.TextFormatter) (logrus.Formatter, bool) {
@@ -100,6 +228,10 @@ func getTextFormatter(formatterArgs Formatter, form *logrus.TextFormatter) (logr
 		form.DisableTimestamp = true
 		dirty = true
 	}
+	if prettyfier := callerPrettyfier(formatterArgs); prettyfier != nil {
+		form.CallerPrettyfier = prettyfier
+		dirty = true
+	}
 	dirty = dirty || (form.TimestampFormat != formatterArgs.TimeFormat)
 	form.TimestampFormat = formatterArgs.TimeFormat
 	return form, dirty
@@ -120,11 +252,34 @@ func getJsonFormatter(formatterArgs Formatter, form *logrus.JSONFormatter) (logr
 		form.DisableTimestamp = true
 		dirty = true
 	}
+	if prettyfier := callerPrettyfier(formatterArgs); prettyfier != nil {
+		form.CallerPrettyfier = prettyfier
+		dirty = true
+	}
 	dirty = dirty || (form.TimestampFormat != formatterArgs.TimeFormat)
 	form.TimestampFormat = formatterArgs.TimeFormat
 	return form, dirty
 }
 
+// Logrus always writes the function, and the file with the line. Skipped values are left empty, which logrus skips
+func callerPrettyfier(formatterArgs Formatter) func(*runtime.Frame) (string, string) {
+	if formatterArgs.FunctionKey != "-" && formatterArgs.FileKey != "-" && formatterArgs.LineKey != "-" {
+		return nil
+	}
+	return func(frame *runtime.Frame) (function string, file string) {
+		if formatterArgs.FunctionKey != "-" {
+			function = frame.Function
+		}
+		if formatterArgs.FileKey != "-" {
+			file = frame.File
+			if formatterArgs.LineKey != "-" {
+				file = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+			}
+		}
+		return function, file
+	}
+}
+
 func setFormatterFieldMap(formatterArgs Formatter) (logrus.FieldMap, bool) {
 	fieldMap := make(logrus.FieldMap)
 
@@ -165,12 +320,138 @@ func setFormatterFieldMap(formatterArgs Formatter) (logrus.FieldMap, bool) {
 		fieldMap[logrus.FieldKeyFunc] = formatterArgs.FunctionKey
 	}
 
+	dirty = dirty || formatterArgs.FileKey != ""
+	switch formatterArgs.FileKey {
+	case "~":
+		fieldMap[logrus.FieldKeyFile] = "file"
+	case "", "-":
+	default:
+		fieldMap[logrus.FieldKeyFile] = formatterArgs.FileKey
+	}
+
 	return fieldMap, dirty
 }
 
+// Sets entry times from a clock instead of time.Now
+type clockHook struct {
+	clock util.Clock
+}
+
+func (h *clockHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *clockHook) Fire(ent *logrus.Entry) error {
+	ent.Time = h.clock.Now()
+	return nil
+}
+
+// Runs TLM's hooks. Logrus has no way to drop an entry or run something after writing it, so the hook marks dropped entries
+// and the formatter does the writing
+type logrusHooks struct {
+	hooks hookChain
+}
+
+type droppedContextKey struct{}
+
+type hookResultContextKey struct{}
+
+func (h *logrusHooks) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logrusHooks) Fire(ent *logrus.Entry) error {
+	ctx := ent.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	entry := entryFromLogrus(ent)
+	if result, ok := ctx.Value(hookResultContextKey{}).(*hookResult); ok {
+		entry = nil
+		if result.entry != nil {
+			// Logrus hooks can change the data, which the sinks may still have
+			copied := *result.entry
+			copied.Fields = make(util.Fields, len(result.entry.Fields))
+			for key, value := range result.entry.Fields {
+				copied.Fields[key] = value
+			}
+			if !ent.HasCaller() {
+				copied.Caller = nil
+			}
+			entry = &copied
+		}
+	} else if !h.hooks.before(entry) {
+		entry = nil
+	}
+	if entry == nil {
+		ent.Context = context.WithValue(ctx, droppedContextKey{}, true)
+		return nil
+	}
+
+	ent.Time = entry.Time
+	if level, ok := convertLogLevel(entry.Level); ok {
+		ent.Level = level
+	}
+	ent.Message = entry.Message
+	ent.Caller = entry.Caller
+	ent.Data = logrus.Fields(entry.Fields)
+	if ent.Data == nil {
+		ent.Data = make(logrus.Fields)
+	}
+	ent.Context = context.WithValue(ctx, fieldOrderContextKey{}, entry.FieldOrder)
+	return nil
+}
+
+// Writes entries to the logger's Out itself so After hooks run once they're written. Logrus is left writing nothing
+type logrusHookFormatter struct {
+	formatter logrus.Formatter
+	hooks     hookChain
+	lock      sync.Mutex
+}
+
+func (f *logrusHookFormatter) Format(ent *logrus.Entry) ([]byte, error) {
+	if ent.Context != nil && ent.Context.Value(droppedContextKey{}) != nil {
+		return nil, nil
+	}
+	data, err := f.formatter.Format(ent)
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.Lock()
+	_, err = ent.Logger.Out.Write(data)
+	f.lock.Unlock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		return nil, nil
+	}
+	f.hooks.after(entryFromLogrus(ent))
+	return nil, nil
+}
+
+func (r *LogrusImpl) WithHooks(hooks []Hook) Logger {
+	logger := r.logrusLogger()
+	logger.AddHook(&logrusHooks{hooks: hooks})
+	logger.Formatter = &logrusHookFormatter{formatter: logger.Formatter, hooks: hooks}
+	return r
+}
+
+func (r *LogrusImpl) withHookResult(result *hookResult) Logger {
+	entry := r.Entry
+	if entry == nil {
+		entry = logrus.NewEntry(r.Logger)
+	}
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return r.derive(entry.WithContext(context.WithValue(ctx, hookResultContextKey{}, result)))
+}
+
 // This is a log hook to replace the call frame so that the logger is called, it gets what actually called the logger instead of the TLM
 func (l *LogrusImpl) Levels() []logrus.Level {
 	levels := []logrus.Level{
+		logrus.TraceLevel,
 		logrus.DebugLevel,
 		logrus.InfoLevel,
 		logrus.WarnLevel,
@@ -201,48 +482,94 @@ func (l LogrusImpl) Fire(ent *logrus.Entry) error {
 		return nil
 	}
 
-	// Try to save some execution time
-	switch getPackageName(ent.Caller.Function) {
-	case LoggingPackageName:
-	case LogrusPackageName:
-	default:
-		// Not from logrus or logging framework... we're probably fine
+	skip := 0
+	if ent.Context != nil {
+		skip, _ = ent.Context.Value(callerSkipContextKey{}).(int)
+	}
+	// Try to save some execution time, as logrus already found a caller outside of itself
+	if skip == 0 && !skipCallerPackage(getPackageName(ent.Caller.Function)) {
 		return nil
 	}
+	// Logrus only skips its own package, so its caller is usually TLM
+	if caller, ok := findCaller(skip); ok {
+		ent.Caller = caller
+	}
+	return nil
+}
 
-	// Based off Logrus as it's pretty compact in functionality
-	pcs := make([]uintptr, maxFrameCount)
-	depth := runtime.Callers(callerMinFrameSkip, pcs)
-	frames := runtime.CallersFrames(pcs[:depth])
-
-	for f, again := frames.Next(); again; f, again = frames.Next() {
-		pkg := getPackageName(f.Function)
+// Get the logrus logger, which is shared by every logger derived from the one InitLogrus created
+func (r *LogrusImpl) logrusLogger() *logrus.Logger {
+	if r.Entry != nil {
+		return r.Entry.Logger
+	}
+	return r.Logger
+}
 
-		if pkg != LoggingPackageName && pkg != LogrusPackageName {
-			ent.Caller = &f
-			return nil
-		}
+// Logrus panics with the entry. Use the message instead, which is what the other loggers panic with
+func unwrapPanicValue(value any) any {
+	if entry, ok := value.(*logrus.Entry); ok {
+		return entry.Message
 	}
+	return value
+}
 
-	// This simply writes to stderr and then uses the existing caller
-	return errors.New("could not find caller")
+func (r *LogrusImpl) WithExitFunc(exitFunc func(int)) Logger {
+	r.logrusLogger().ExitFunc = exitFunc
+	return r
 }
 
 // Hidden-function used for testing
 func (r *LogrusImpl) testExitFunc(exitHandler func(int)) bool {
-	if r.Entry != nil {
-		return false
-	}
-	r.Log.ExitFunc = exitHandler
+	r.logrusLogger().ExitFunc = exitHandler
 	return true
 }
 
 // Fields
+
+// logrus doesn't keep the order fields are added in, so it's stored in the entry context for TLM's formatters
+type fieldOrderContextKey struct{}
+
+type callerSkipContextKey struct{}
+
+func (r *LogrusImpl) WithCallerSkip(skip int) Logger {
+	entry := r.Entry
+	if entry == nil {
+		entry = logrus.NewEntry(r.Logger)
+	}
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	current, _ := ctx.Value(callerSkipContextKey{}).(int)
+	return r.derive(entry.WithContext(context.WithValue(ctx, callerSkipContextKey{}, current+skip)))
+}
+
+// Get a logger for the entry with the same settings
+func (r *LogrusImpl) derive(entry *logrus.Entry) *LogrusImpl {
+	derived := *r
+	derived.Logger = nil
+	derived.Entry = entry
+	return &derived
+}
+
+func (r *LogrusImpl) withFieldOrder(entry *logrus.Entry, keys ...string) *logrus.Entry {
+	if !r.fieldOrder {
+		return entry
+	}
+	ctx := context.Background()
+	var order []string
+	if r.Entry != nil && r.Entry.Context != nil {
+		ctx = r.Entry.Context
+		order, _ = ctx.Value(fieldOrderContextKey{}).([]string)
+	}
+	return entry.WithContext(context.WithValue(ctx, fieldOrderContextKey{}, appendFieldOrder(order, keys...)))
+}
+
 func (r *LogrusImpl) WithField(key string, value any) Logger {
 	if r.Entry != nil {
-		return &LogrusImpl{Entry: r.Entry.WithField(key, value)}
+		return r.derive(r.withFieldOrder(r.Entry.WithField(key, value), key))
 	}
-	return &LogrusImpl{Entry: r.Log.WithField(key, value)}
+	return r.derive(r.withFieldOrder(r.Logger.WithField(key, value), key))
 }
 
 func (r *LogrusImpl) WithFields(fields util.Fields) Logger {
@@ -250,33 +577,94 @@ func (r *LogrusImpl) WithFields(fields util.Fields) Logger {
 	for key, value := range fields {
 		logFields[key] = value
 	}
+	var keys []string
+	if r.fieldOrder {
+		keys = sortedFieldKeys(fields)
+	}
 	if r.Entry != nil {
-		return &LogrusImpl{Entry: r.Entry.WithFields(logFields)}
+		return r.derive(r.withFieldOrder(r.Entry.WithFields(logFields), keys...))
+	}
+	return r.derive(r.withFieldOrder(r.Logger.WithFields(logFields), keys...))
+}
+
+func (r *LogrusImpl) WithError(err error) Logger {
+	if err == nil {
+		return r
+	}
+	errorKey := r.errorKey
+	if errorKey == "" {
+		errorKey = logrus.ErrorKey
 	}
-	return &LogrusImpl{Entry: r.Log.WithFields(logFields)}
+	return r.WithFields(errorFields(err, errorKey, r.captureErrorStack))
+}
+
+func (r *LogrusImpl) V(level int) Logger {
+	if level > r.Verbosity {
+		return &nullLoggerType{exitFunc: r.logrusLogger().ExitFunc}
+	}
+	return r
 }
 
 // Logging function calls
+func (r *LogrusImpl) Log(level LogLevel, args ...any) {
+	logAtLevel(r, level, args...)
+}
+func (r *LogrusImpl) Logf(level LogLevel, format string, args ...any) {
+	logfAtLevel(r, level, format, args...)
+}
+func (r *LogrusImpl) Enabled(level LogLevel) bool {
+	logrusLevel, ok := convertLogLevel(level)
+	if !ok {
+		return false
+	}
+	if r.Entry != nil {
+		return r.Entry.Logger.IsLevelEnabled(logrusLevel)
+	}
+	return r.Logger.IsLevelEnabled(logrusLevel)
+}
+
+func (r *LogrusImpl) Tracef(format string, args ...any) {
+	if r.Entry != nil {
+		r.Entry.Tracef(format, args...)
+		return
+	}
+	r.Logger.Tracef(format, args...)
+}
+func (r *LogrusImpl) Trace(args ...any) {
+	if r.Entry != nil {
+		r.Entry.Trace(args...)
+		return
+	}
+	r.Logger.Trace(args...)
+}
+func (r *LogrusImpl) Traceln(args ...any) {
+	if r.Entry != nil {
+		r.Entry.Traceln(args...)
+		return
+	}
+	r.Logger.Traceln(args...)
+}
+
 func (r *LogrusImpl) Debugf(format string, args ...any) {
 	if r.Entry != nil {
 		r.Entry.Debugf(format, args...)
 		return
 	}
-	r.Log.Debugf(format, args...)
+	r.Logger.Debugf(format, args...)
 }
 func (r *LogrusImpl) Debug(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Debug(args...)
 		return
 	}
-	r.Log.Debug(args...)
+	r.Logger.Debug(args...)
 }
 func (r *LogrusImpl) Debugln(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Debugln(args...)
 		return
 	}
-	r.Log.Debugln(args...)
+	r.Logger.Debugln(args...)
 }
 
 func (r *LogrusImpl) Infof(format string, args ...any) {
@@ -284,21 +672,21 @@ func (r *LogrusImpl) Infof(format string, args ...any) {
 		r.Entry.Infof(format, args...)
 		return
 	}
-	r.Log.Infof(format, args...)
+	r.Logger.Infof(format, args...)
 }
 func (r *LogrusImpl) Info(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Info(args...)
 		return
 	}
-	r.Log.Info(args...)
+	r.Logger.Info(args...)
 }
 func (r *LogrusImpl) Infoln(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Infoln(args...)
 		return
 	}
-	r.Log.Infoln(args...)
+	r.Logger.Infoln(args...)
 }
 
 func (r *LogrusImpl) Warnf(format string, args ...any) {
@@ -306,21 +694,21 @@ func (r *LogrusImpl) Warnf(format string, args ...any) {
 		r.Entry.Warnf(format, args...)
 		return
 	}
-	r.Log.Warnf(format, args...)
+	r.Logger.Warnf(format, args...)
 }
 func (r *LogrusImpl) Warn(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Warn(args...)
 		return
 	}
-	r.Log.Warn(args...)
+	r.Logger.Warn(args...)
 }
 func (r *LogrusImpl) Warnln(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Warnln(args...)
 		return
 	}
-	r.Log.Warnln(args...)
+	r.Logger.Warnln(args...)
 }
 
 func (r *LogrusImpl) Errorf(format string, args ...any) {
@@ -328,21 +716,21 @@ func (r *LogrusImpl) Errorf(format string, args ...any) {
 		r.Entry.Errorf(format, args...)
 		return
 	}
-	r.Log.Errorf(format, args...)
+	r.Logger.Errorf(format, args...)
 }
 func (r *LogrusImpl) Error(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Error(args...)
 		return
 	}
-	r.Log.Error(args...)
+	r.Logger.Error(args...)
 }
 func (r *LogrusImpl) Errorln(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Errorln(args...)
 		return
 	}
-	r.Log.Errorln(args...)
+	r.Logger.Errorln(args...)
 }
 
 func (r *LogrusImpl) Panicf(format string, args ...any) {
@@ -350,21 +738,21 @@ func (r *LogrusImpl) Panicf(format string, args ...any) {
 		r.Entry.Panicf(format, args...)
 		return
 	}
-	r.Log.Panicf(format, args...)
+	r.Logger.Panicf(format, args...)
 }
 func (r *LogrusImpl) Panic(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Panic(args...)
 		return
 	}
-	r.Log.Panic(args...)
+	r.Logger.Panic(args...)
 }
 func (r *LogrusImpl) Panicln(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Panicln(args...)
 		return
 	}
-	r.Log.Panicln(args...)
+	r.Logger.Panicln(args...)
 }
 
 func (r *LogrusImpl) Fatalf(format string, args ...any) {
@@ -372,19 +760,19 @@ func (r *LogrusImpl) Fatalf(format string, args ...any) {
 		r.Entry.Fatalf(format, args...)
 		return
 	}
-	r.Log.Fatalf(format, args...)
+	r.Logger.Fatalf(format, args...)
 }
 func (r *LogrusImpl) Fatal(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Fatal(args...)
 		return
 	}
-	r.Log.Fatal(args...)
+	r.Logger.Fatal(args...)
 }
 func (r *LogrusImpl) Fatalln(args ...any) {
 	if r.Entry != nil {
 		r.Entry.Fatalln(args...)
 		return
 	}
-	r.Log.Fatalln(args...)
+	r.Logger.Fatalln(args...)
 }
@@ -72,11 +72,20 @@ func TestLevels(t *testing.T) {
 		args     args
 		expected string
 	}{
+		{
+			name: "Trace",
+			args: args{
+				level:      logging.TraceLevel,
+				ignoreCase: func(_ logging.Logger) {}, // Nothing that trace wouldn't cover...
+				levelCase:  func(logger logging.Logger) { logger.Trace("TraceSuccess") },
+			},
+			expected: "TraceSuccess",
+		},
 		{
 			name: "Debug",
 			args: args{
 				level:      logging.DebugLevel,
-				ignoreCase: func(_ logging.Logger) {}, // Nothing that debug wouldn't cover...
+				ignoreCase: func(logger logging.Logger) { logger.Trace("DebugFail") },
 				levelCase:  func(logger logging.Logger) { logger.Debug("DebugSuccess") },
 			},
 			expected: "DebugSuccess",
@@ -484,6 +493,146 @@ func TestFormats(t *testing.T) {
 	}
 }
 
+func TestLogrusVerbosity(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Verbosity = 2
+	logger, buffer := createLogger(logArgs)
+
+	logger.V(3).Info("VerboseFail")
+	util.AssertEqual(t, buffer.Len(), 0, "buffer length")
+
+	logger.V(2).Info("VerboseSuccess")
+	util.AssertContains(t, buffer.String(), "VerboseSuccess", "contents")
+	util.AssertNotContains(t, buffer.String(), "VerboseFail", "contents")
+}
+
+func TestLogrusEnabled(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.Level = logging.WarnLevel
+	logger, buffer := createLogger(logArgs)
+
+	util.AssertEqual(t, logger.Enabled(logging.InfoLevel), false, "info enabled")
+	util.AssertEqual(t, logger.Enabled(logging.WarnLevel), true, "warn enabled")
+	util.AssertEqual(t, logger.WithField("key", "value").Enabled(logging.ErrorLevel), true, "error enabled")
+
+	logger.Log(logging.InfoLevel, "LogFail")
+	util.AssertEqual(t, buffer.Len(), 0, "buffer length")
+	logger.Logf(logging.ErrorLevel, "Log%s", "Success")
+	util.AssertContains(t, buffer.String(), "LogSuccess", "contents")
+}
+
+type countingHook struct {
+	fired int
+}
+
+func (h *countingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *countingHook) Fire(_ *logrus.Entry) error {
+	h.fired++
+	return nil
+}
+
+func TestLogrusOptions(t *testing.T) {
+	hook := new(countingHook)
+	tests := []struct {
+		name      string
+		formatter logging.FormatterType
+		options   any
+		contains  []string
+		missing   []string
+	}{
+		{
+			name:      "No Options",
+			formatter: logging.JsonFormat,
+			options:   nil,
+			contains:  []string{"\"myField\":\"value\""},
+			missing:   []string{"\n  ", "\"func\""},
+		},
+		{
+			name:      "Pretty Print",
+			formatter: logging.JsonFormat,
+			options:   logging.LogrusOptions{PrettyPrint: true},
+			contains:  []string{"\n  \"myField\": \"value\""},
+		},
+		{
+			name:      "Pretty Print Pointer",
+			formatter: logging.JsonFormat,
+			options:   &logging.LogrusOptions{PrettyPrint: true},
+			contains:  []string{"\n  \"myField\": \"value\""},
+		},
+		{
+			name:      "Data Key",
+			formatter: logging.JsonFormat,
+			options:   logging.LogrusOptions{DataKey: "data"},
+			contains:  []string{"\"data\":{\"myField\":\"value\"}"},
+		},
+		{
+			name:      "Report Caller",
+			formatter: logging.JsonFormat,
+			options:   logging.LogrusOptions{ReportCaller: true},
+			contains:  []string{"\"func\":\"github.com/rcmaniac25/tlm/logging_test.TestLogrusOptions"},
+		},
+		{
+			name:      "Pad Level",
+			formatter: logging.TextFormat,
+			options:   logging.LogrusOptions{PadLevelText: true, ForceColors: true},
+			contains:  []string{"INFO   "},
+		},
+		{
+			name:      "Disable Quote",
+			formatter: logging.TextFormat,
+			options:   logging.LogrusOptions{DisableQuote: true},
+			contains:  []string{"msg=Hello World"},
+		},
+		{
+			name:      "Force Colors",
+			formatter: logging.TextFormat,
+			options:   logging.LogrusOptions{ForceColors: true},
+			contains:  []string{"\x1b["},
+		},
+		{
+			name:      "Disable Colors",
+			formatter: logging.TextFormat,
+			options:   logging.LogrusOptions{ForceColors: true, DisableColors: true},
+			missing:   []string{"\x1b["},
+		},
+		{
+			name:      "Hooks",
+			formatter: logging.JsonFormat,
+			options:   logging.LogrusOptions{Hooks: []logrus.Hook{hook}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logArgs := new(logging.TLMLoggingInitialization)
+			logArgs.Formatter.Type = test.formatter
+			if test.options != nil {
+				logArgs.SetBackendOptions(logging.LogrusLogType.String(), test.options)
+			}
+			logger, buffer := createLogger(logArgs)
+
+			logger.WithField("myField", "value").Info("Hello World")
+
+			for _, contains := range test.contains {
+				util.AssertContains(t, buffer.String(), contains, "contents")
+			}
+			for _, missing := range test.missing {
+				util.AssertNotContains(t, buffer.String(), missing, "contents")
+			}
+		})
+	}
+	util.AssertEqual(t, hook.fired, 1, "hook fired")
+}
+
+func TestLogrusOptionsWrongType(t *testing.T) {
+	logArgs := new(logging.TLMLoggingInitialization)
+	logArgs.SetBackendOptions(logging.LogrusLogType.String(), "not logrus options")
+	_, err := logging.InitLogrus(logArgs)
+	util.AssertError(t, err, "wrong type")
+}
+
 func TestSanity(t *testing.T) {
 	// This exists as a sanity check for some constants
 
@@ -498,5 +647,5 @@ func TestSanity(t *testing.T) {
 	util.AssertEqual(t, reflect.TypeOf(*lrus).PkgPath(), logging.LoggingPackageName, "package name")
 
 	// We're testing Logrus... if it's something other then Logrus, then this will fail
-	util.AssertEqual(t, reflect.TypeOf(*lrus.Log).PkgPath(), logging.LogrusPackageName, "logrus package name")
+	util.AssertEqual(t, reflect.TypeOf(lrus.Logger).Elem().PkgPath(), logging.LogrusPackageName, "logrus package name")
 }
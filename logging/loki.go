@@ -0,0 +1,500 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LokiPushPath = "/loki/api/v1/push"
+
+	lokiTenantHeader = "X-Scope-OrgID"
+
+	// Used by the default client
+	lokiClientTimeout = 30 * time.Second
+	// Batches waiting to be sent. Writes wait once there are this many
+	lokiQueueSize = 10
+)
+
+var errLokiClosed = errors.New("loki sink is closed")
+
+type LokiEncoding int
+
+const (
+	LokiJsonEncoding LokiEncoding = iota
+	// Snappy compressed protobuf
+	LokiProtobufEncoding
+)
+
+func (e LokiEncoding) String() string {
+	switch e {
+	case LokiJsonEncoding:
+		return "json"
+	case LokiProtobufEncoding:
+		return "protobuf"
+	}
+	return "unknown"
+}
+
+type LokiOptions struct {
+	// Loki's address. LokiPushPath is used if the URL has no path
+	URL      string
+	Encoding LokiEncoding
+	// Sent as the X-Scope-OrgID header when set
+	TenantID string
+	// Default is a client with a 30 second timeout
+	Client *http.Client
+
+	// Labels added to every stream
+	Labels map[string]string
+	// Fields that are used as stream labels instead of being written to the line. These should have few values, as
+	// each set of labels is a separate stream
+	LabelFields []string
+	// The label the level is recorded as. "-" means the level isn't a label. Default is "level"
+	LevelLabel string
+	// How the line is formatted. Default is LogfmtFormat without the time, as Loki records the time itself, and without
+	// the level when it's a label
+	LineFormatter *Formatter
+
+	// Entries are sent once the batch is BatchBytes (default 1 MB) or BatchWait has passed (default 1 second)
+	BatchBytes int
+	BatchWait  time.Duration
+
+	// How many times to retry sending a batch, when Loki can't be reached or returns 429 or 5xx. Default is 5, negative
+	// values don't retry
+	MaxRetries int
+	// Wait before the first retry, doubling after each retry up to MaxRetryWait. Defaults are 500ms and 30s
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+}
+
+type lokiEntry struct {
+	time time.Time
+	line string
+}
+
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+type lokiBatch struct {
+	body        []byte
+	contentType string
+	// Set by Flush, which waits for the batches before it to be sent
+	flushed chan error
+}
+
+// Sends entries to Grafana Loki's push API. Batches are sent, and retried, in the background. Flush waits for them to
+// be sent
+type LokiSink struct {
+	options       LokiOptions
+	labelFields   map[string]bool
+	lineFormatter EntryFormatter
+
+	lock         sync.Mutex
+	closed       bool
+	streams      map[string]*lokiStream
+	pendingBytes int
+	queue        chan lokiBatch
+	sent         sync.WaitGroup
+	done         chan struct{}
+	flushed      sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewLokiSink(options LokiOptions) (*LokiSink, error) {
+	if options.URL == "" {
+		return nil, errors.New("'URL' must be set")
+	}
+	pushURL, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, err
+	}
+	if pushURL.Path == "" || pushURL.Path == "/" {
+		pushURL.Path = LokiPushPath
+	}
+	options.URL = pushURL.String()
+
+	if options.Encoding < LokiJsonEncoding || options.Encoding > LokiProtobufEncoding {
+		return nil, fmt.Errorf("unknown loki encoding: %d", options.Encoding)
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: lokiClientTimeout}
+	}
+	if options.LevelLabel == "" {
+		options.LevelLabel = "level"
+	}
+	if options.LineFormatter == nil {
+		options.LineFormatter = &Formatter{Type: LogfmtFormat, TimeKey: "-"}
+		if options.LevelLabel != "-" {
+			options.LineFormatter.LevelKey = "-"
+		}
+	}
+	lineFormatter, err := options.LineFormatter.NewEntryFormatter(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if options.BatchBytes <= 0 {
+		options.BatchBytes = 1024 * 1024
+	}
+	if options.BatchWait <= 0 {
+		options.BatchWait = time.Second
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = 5
+	}
+	if options.RetryWait <= 0 {
+		options.RetryWait = 500 * time.Millisecond
+	}
+	if options.MaxRetryWait <= 0 {
+		options.MaxRetryWait = 30 * time.Second
+	}
+
+	sink := &LokiSink{
+		options:       options,
+		labelFields:   make(map[string]bool, len(options.LabelFields)),
+		lineFormatter: lineFormatter,
+		streams:       make(map[string]*lokiStream),
+		queue:         make(chan lokiBatch, lokiQueueSize),
+		done:          make(chan struct{}),
+	}
+	for _, field := range options.LabelFields {
+		sink.labelFields[field] = true
+	}
+	sink.sent.Add(1)
+	go sink.sendLoop()
+	sink.flushed.Add(1)
+	go sink.flushLoop()
+	return sink, nil
+}
+
+// Label names must match [a-zA-Z_][a-zA-Z0-9_]*
+func lokiLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// The Prometheus style label set, which identifies a stream. Example: {job="app", level="info"}
+func lokiLabelString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Split an entry into its stream labels and line
+func (l *LokiSink) streamEntry(entry *Entry) (map[string]string, lokiEntry, error) {
+	labels := make(map[string]string, len(l.options.Labels)+len(l.labelFields)+1)
+	for name, value := range l.options.Labels {
+		labels[lokiLabelName(name)] = value
+	}
+	if l.options.LevelLabel != "-" {
+		labels[lokiLabelName(l.options.LevelLabel)] = entry.Level.String()
+	}
+
+	lineEntry := *entry
+	lineEntry.Fields = make(map[string]any, len(entry.Fields))
+	for key, value := range entry.Fields {
+		if l.labelFields[key] {
+			labels[lokiLabelName(key)] = fmt.Sprint(value)
+		} else {
+			lineEntry.Fields[key] = value
+		}
+	}
+
+	line, err := l.lineFormatter.Format(&lineEntry)
+	if err != nil {
+		return nil, lokiEntry{}, err
+	}
+	return labels, lokiEntry{time: entry.Time, line: strings.TrimSuffix(string(line), "\n")}, nil
+}
+
+func (l *LokiSink) Write(entry *Entry) error {
+	labels, streamEntry, err := l.streamEntry(entry)
+	if err != nil {
+		return err
+	}
+	key := lokiLabelString(labels)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return errLokiClosed
+	}
+
+	stream, ok := l.streams[key]
+	if !ok {
+		stream = &lokiStream{labels: labels}
+		l.streams[key] = stream
+	}
+	stream.entries = append(stream.entries, streamEntry)
+	l.pendingBytes += len(streamEntry.line)
+	if l.pendingBytes >= l.options.BatchBytes {
+		return l.flush()
+	}
+	return nil
+}
+
+func (l *LokiSink) flushLoop() {
+	defer l.flushed.Done()
+
+	ticker := time.NewTicker(l.options.BatchWait)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			l.lock.Lock()
+			if err := l.flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+			}
+			l.lock.Unlock()
+		}
+	}
+}
+
+// Send queued batches until the queue is closed. Errors are reported to Flush as well
+func (l *LokiSink) sendLoop() {
+	defer l.sent.Done()
+
+	var sendErr error
+	for batch := range l.queue {
+		if batch.flushed != nil {
+			batch.flushed <- sendErr
+			sendErr = nil
+			continue
+		}
+		if err := l.send(batch.body, batch.contentType); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+			if sendErr == nil {
+				sendErr = err
+			}
+		}
+	}
+}
+
+// Queue the pending entries to be sent. Must be called with the lock held
+func (l *LokiSink) flush() error {
+	if len(l.streams) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(l.streams))
+	for key := range l.streams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	streams := make([]*lokiStream, 0, len(keys))
+	for _, key := range keys {
+		streams = append(streams, l.streams[key])
+	}
+	l.streams = make(map[string]*lokiStream)
+	l.pendingBytes = 0
+
+	var body []byte
+	var contentType string
+	if l.options.Encoding == LokiProtobufEncoding {
+		body = snappyEncode(lokiProtobufRequest(keys, streams))
+		contentType = "application/x-protobuf"
+	} else {
+		var err error
+		if body, err = lokiJsonRequest(streams); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	l.queue <- lokiBatch{body: body, contentType: contentType}
+	return nil
+}
+
+func lokiJsonRequest(streams []*lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{Streams: make([]jsonStream, 0, len(streams))}
+
+	for _, stream := range streams {
+		values := make([][2]string, 0, len(stream.entries))
+		for _, entry := range stream.entries {
+			values = append(values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line})
+		}
+		request.Streams = append(request.Streams, jsonStream{Stream: stream.labels, Values: values})
+	}
+	return json.Marshal(request)
+}
+
+// Protobuf encoding of logproto.PushRequest:
+//
+//	PushRequest { repeated StreamAdapter streams = 1; }
+//	StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	Timestamp { int64 seconds = 1; int32 nanos = 2; }
+func lokiProtobufRequest(labels []string, streams []*lokiStream) []byte {
+	var request bytes.Buffer
+	for i, stream := range streams {
+		var streamMessage bytes.Buffer
+		writeProtobufBytes(&streamMessage, 1, []byte(labels[i]))
+		for _, entry := range stream.entries {
+			var timestamp bytes.Buffer
+			writeProtobufVarint(&timestamp, 1, uint64(entry.time.Unix()))
+			writeProtobufVarint(&timestamp, 2, uint64(entry.time.Nanosecond()))
+
+			var entryMessage bytes.Buffer
+			writeProtobufBytes(&entryMessage, 1, timestamp.Bytes())
+			writeProtobufBytes(&entryMessage, 2, []byte(entry.line))
+			writeProtobufBytes(&streamMessage, 2, entryMessage.Bytes())
+		}
+		writeProtobufBytes(&request, 1, streamMessage.Bytes())
+	}
+	return request.Bytes()
+}
+
+func writeProtobufUvarint(b *bytes.Buffer, value uint64) {
+	var data [binary.MaxVarintLen64]byte
+	b.Write(data[:binary.PutUvarint(data[:], value)])
+}
+
+// Zero values are skipped, as proto3 does
+func writeProtobufVarint(b *bytes.Buffer, field int, value uint64) {
+	if value == 0 {
+		return
+	}
+	writeProtobufUvarint(b, uint64(field)<<3)
+	writeProtobufUvarint(b, value)
+}
+
+func writeProtobufBytes(b *bytes.Buffer, field int, value []byte) {
+	writeProtobufUvarint(b, uint64(field)<<3|2)
+	writeProtobufUvarint(b, uint64(len(value)))
+	b.Write(value)
+}
+
+// Send a batch, retrying with an exponential backoff if Loki can't take it right now
+func (l *LokiSink) send(body []byte, contentType string) error {
+	wait := l.options.RetryWait
+	for attempt := 0; ; attempt++ {
+		retry, err := l.sendOnce(body, contentType)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= l.options.MaxRetries {
+			return err
+		}
+
+		time.Sleep(wait)
+		wait *= 2
+		if wait > l.options.MaxRetryWait {
+			wait = l.options.MaxRetryWait
+		}
+	}
+}
+
+func (l *LokiSink) sendOnce(body []byte, contentType string) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, l.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", contentType)
+	if l.options.TenantID != "" {
+		request.Header.Set(lokiTenantHeader, l.options.TenantID)
+	}
+
+	response, err := l.options.Client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		io.Copy(io.Discard, response.Body)
+		return false, nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	err = fmt.Errorf("loki push failed with %s: %s", response.Status, strings.TrimSpace(string(message)))
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode/100 == 5, err
+}
+
+// Wait for the entries written so far to be sent. Returns the first error sending them since the last Flush
+func (l *LokiSink) Flush() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return errLokiClosed
+	}
+	flushed, err := l.queueFlush()
+	l.lock.Unlock()
+	if sendErr := <-flushed; err == nil {
+		err = sendErr
+	}
+	return err
+}
+
+// Queue the pending entries and a batch that's answered once they're sent. Must be called with the lock held
+func (l *LokiSink) queueFlush() (chan error, error) {
+	err := l.flush()
+	flushed := make(chan error, 1)
+	l.queue <- lokiBatch{flushed: flushed}
+	return flushed, err
+}
+
+// Sends any pending entries
+func (l *LokiSink) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.flushed.Wait()
+
+		l.lock.Lock()
+		l.closed = true
+		flushed, err := l.queueFlush()
+		close(l.queue)
+		l.lock.Unlock()
+
+		if sendErr := <-flushed; err == nil {
+			err = sendErr
+		}
+		l.sent.Wait()
+		l.closeErr = err
+	})
+	return l.closeErr
+}
@@ -0,0 +1,375 @@
+package logging_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+type lokiPush struct {
+	tenant  string
+	streams map[string][]lokiTestEntry
+}
+
+type lokiTestEntry struct {
+	time time.Time
+	line string
+}
+
+// Decode a snappy block
+func decodeSnappy(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errors.New("invalid snappy length")
+	}
+	src = src[n:]
+	dst := make([]byte, 0, length)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 0x03 {
+		case 0x00:
+			size := int(tag>>2) + 1
+			src = src[1:]
+			if size > 60 {
+				extra := size - 60
+				size = 1
+				for i := 0; i < extra; i++ {
+					size += int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			dst = append(dst, src[:size]...)
+			src = src[size:]
+		case 0x02:
+			size := int(tag>>2) + 1
+			offset := int(src[1]) | int(src[2])<<8
+			for i := 0; i < size; i++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+			src = src[3:]
+		default:
+			return nil, fmt.Errorf("unexpected snappy tag: %x", tag)
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, errors.New("snappy length mismatch")
+	}
+	return dst, nil
+}
+
+// Split a protobuf message into its fields. Only varint and length delimited fields are supported
+func decodeProtobuf(data []byte) (map[int][][]byte, map[int]uint64) {
+	messages := make(map[int][][]byte)
+	varints := make(map[int]uint64)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		field := int(key >> 3)
+		if key&0x07 == 0 {
+			value, n := binary.Uvarint(data)
+			varints[field] = value
+			data = data[n:]
+			continue
+		}
+		length, n := binary.Uvarint(data)
+		data = data[n:]
+		messages[field] = append(messages[field], data[:length])
+		data = data[length:]
+	}
+	return messages, varints
+}
+
+func decodeLokiProtobuf(t *testing.T, body []byte) map[string][]lokiTestEntry {
+	data, err := decodeSnappy(body)
+	util.AssertNoError(t, err, "snappy")
+
+	streams := make(map[string][]lokiTestEntry)
+	request, _ := decodeProtobuf(data)
+	for _, streamData := range request[1] {
+		stream, _ := decodeProtobuf(streamData)
+		labels := string(stream[1][0])
+		for _, entryData := range stream[2] {
+			entry, _ := decodeProtobuf(entryData)
+			_, timestamp := decodeProtobuf(entry[1][0])
+			streams[labels] = append(streams[labels], lokiTestEntry{
+				time: time.Unix(int64(timestamp[1]), int64(timestamp[2])),
+				line: string(entry[2][0]),
+			})
+		}
+	}
+	return streams
+}
+
+func decodeLokiJson(t *testing.T, body []byte) map[string][]lokiTestEntry {
+	var request struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	util.AssertNoError(t, json.Unmarshal(body, &request), "json")
+
+	// Use the same label format as protobuf so the tests can check either
+	streams := make(map[string][]lokiTestEntry)
+	for _, stream := range request.Streams {
+		labels := make([]string, 0, len(stream.Stream))
+		for name, value := range stream.Stream {
+			labels = append(labels, name+"="+strconv.Quote(value))
+		}
+		sort.Strings(labels)
+		key := "{" + strings.Join(labels, ", ") + "}"
+		for _, value := range stream.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			util.AssertNoError(t, err, "timestamp")
+			streams[key] = append(streams[key], lokiTestEntry{time: time.Unix(0, ns), line: value[1]})
+		}
+	}
+	return streams
+}
+
+// Stand-in for Loki. Responds with the status codes in order, then with 204
+func startLokiServer(t *testing.T, statuses ...int) (*httptest.Server, chan lokiPush) {
+	pushes := make(chan lokiPush, 10)
+	var lock sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		status := http.StatusNoContent
+		if len(statuses) > 0 {
+			status = statuses[0]
+			statuses = statuses[1:]
+		}
+		lock.Unlock()
+		if status != http.StatusNoContent {
+			http.Error(w, "try again", status)
+			return
+		}
+
+		util.AssertEqual(t, r.URL.Path, logging.LokiPushPath, "path")
+		body, err := io.ReadAll(r.Body)
+		util.AssertNoError(t, err, "body")
+
+		push := lokiPush{tenant: r.Header.Get("X-Scope-OrgID")}
+		if r.Header.Get("Content-Type") == "application/x-protobuf" {
+			push.streams = decodeLokiProtobuf(t, body)
+		} else {
+			util.AssertEqual(t, r.Header.Get("Content-Type"), "application/json", "content type")
+			push.streams = decodeLokiJson(t, body)
+		}
+		pushes <- push
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return server, pushes
+}
+
+func nextLokiPush(t *testing.T, pushes chan lokiPush) lokiPush {
+	select {
+	case push := <-pushes:
+		return push
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for push")
+	}
+	return lokiPush{}
+}
+
+func TestLoki(t *testing.T) {
+	for _, encoding := range []logging.LokiEncoding{logging.LokiJsonEncoding, logging.LokiProtobufEncoding} {
+		t.Run(encoding.String(), func(t *testing.T) {
+			server, pushes := startLokiServer(t)
+			defer server.Close()
+
+			sink, err := logging.NewLokiSink(logging.LokiOptions{
+				URL:         server.URL,
+				Encoding:    encoding,
+				TenantID:    "team-a",
+				Labels:      map[string]string{"job": "test"},
+				LabelFields: []string{"component"},
+			})
+			util.AssertNoError(t, err, "sink")
+
+			logger := startSinkLogger(t, sink, nil)
+			logTime := time.Now()
+			logger.WithFields(util.Fields{"component": "db", "query": "select 1"}).Info("Queried")
+			logger.WithField("component", "db").Warn("Slow")
+			logger.WithField("component", "api").Info(strings.Repeat("repeated ", 20))
+
+			// Closing sends the batch
+			util.AssertNoError(t, sink.Close(), "close")
+			push := nextLokiPush(t, pushes)
+			util.AssertEqual(t, push.tenant, "team-a", "tenant")
+			util.AssertEqual(t, len(push.streams), 3, "stream count")
+
+			db := push.streams[`{component="db", job="test", level="info"}`]
+			util.AssertEqual(t, len(db), 1, "db entries")
+			util.AssertEqual(t, db[0].line, `msg=Queried query="select 1"`, "line")
+			util.AssertEqual(t, db[0].time.Sub(logTime) < time.Second && db[0].time.Sub(logTime) > -time.Second, true, "time")
+
+			slow := push.streams[`{component="db", job="test", level="warn"}`]
+			util.AssertEqual(t, len(slow), 1, "warn entries")
+			util.AssertEqual(t, slow[0].line, "msg=Slow", "line")
+
+			api := push.streams[`{component="api", job="test", level="info"}`]
+			util.AssertEqual(t, len(api), 1, "api entries")
+			util.AssertEqual(t, api[0].line, fmt.Sprintf("msg=\"%s\"", strings.Repeat("repeated ", 20)), "compressed line")
+		})
+	}
+}
+
+func TestLokiBatching(t *testing.T) {
+	server, pushes := startLokiServer(t)
+	defer server.Close()
+
+	sink, err := logging.NewLokiSink(logging.LokiOptions{
+		URL:        server.URL,
+		LevelLabel: "-",
+		BatchBytes: 40,
+		BatchWait:  100 * time.Millisecond,
+	})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	// A full batch is sent right away
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "first"}), "write")
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: strings.Repeat("a", 40)}), "write")
+	push := nextLokiPush(t, pushes)
+	util.AssertEqual(t, len(push.streams["{}"]), 2, "batch entries")
+	util.AssertEqual(t, push.streams["{}"][0].line, "level=info msg=first", "level in line")
+
+	// Otherwise the batch is sent after waiting
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "later"}), "write")
+	push = nextLokiPush(t, pushes)
+	util.AssertEqual(t, len(push.streams["{}"]), 1, "waited entries")
+}
+
+func TestLokiRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		success  bool
+	}{
+		{
+			name:     "Retried",
+			statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+			success:  true,
+		},
+		{
+			name:     "Bad Request",
+			statuses: []int{http.StatusBadRequest},
+			success:  false,
+		},
+		{
+			name:     "Too Many Retries",
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			success:  false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, pushes := startLokiServer(t, test.statuses...)
+			defer server.Close()
+
+			sink, err := logging.NewLokiSink(logging.LokiOptions{
+				URL:        server.URL,
+				MaxRetries: 2,
+				RetryWait:  time.Millisecond,
+				BatchBytes: 1,
+			})
+			util.AssertNoError(t, err, "sink")
+			defer sink.Close()
+
+			// Batches are sent in the background, so the error comes from Flush
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "retry"}), "write")
+			err = sink.Flush()
+			if test.success {
+				util.AssertNoError(t, err, "flush")
+				util.AssertEqual(t, len(nextLokiPush(t, pushes).streams), 1, "pushed")
+			} else {
+				util.AssertError(t, err, "flush")
+			}
+		})
+	}
+}
+
+func TestLokiClose(t *testing.T) {
+	server, pushes := startLokiServer(t)
+	defer server.Close()
+
+	sink, err := logging.NewLokiSink(logging.LokiOptions{URL: server.URL})
+	util.AssertNoError(t, err, "sink")
+
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "sent"}), "write")
+	util.AssertNoError(t, sink.Close(), "close")
+	util.AssertNoError(t, sink.Close(), "close twice")
+	util.AssertError(t, sink.Write(&logging.Entry{Time: time.Now(), Level: logging.InfoLevel, Message: "closed"}), "write after close")
+	util.AssertError(t, sink.Flush(), "flush after close")
+	util.AssertEqual(t, len(nextLokiPush(t, pushes).streams), 1, "pushed")
+}
+
+func TestLokiOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options logging.LokiOptions
+	}{
+		{
+			name:    "No URL",
+			options: logging.LokiOptions{},
+		},
+		{
+			name:    "Unknown Encoding",
+			options: logging.LokiOptions{URL: "http://localhost:3100", Encoding: 5},
+		},
+		{
+			name:    "Unknown Line Format",
+			options: logging.LokiOptions{URL: "http://localhost:3100", LineFormatter: &logging.Formatter{Type: logging.TextFormat}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := logging.NewLokiSink(test.options)
+			util.AssertError(t, err, fmt.Sprintf("options: %+v", test.options))
+		})
+	}
+}
+
+func TestLokiAlongsideLogrus(t *testing.T) {
+	server, pushes := startLokiServer(t)
+	defer server.Close()
+
+	sink, err := logging.NewLokiSink(logging.LokiOptions{URL: server.URL, LevelLabel: "-", BatchWait: time.Hour})
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	exited := false
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:     logging.LogrusLogType,
+		Output:   io.Discard,
+		Sinks:    []logging.Sink{sink},
+		ExitFunc: func(int) { exited = true },
+	}
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	// Nothing else sends the batch before the sink is closed
+	util.AssertPanic(t, func() { tlm.Log(ctx).Panic("Panicked") }, "panic")
+	push := nextLokiPush(t, pushes)
+	util.AssertEqual(t, push.streams["{}"][0].line, "level=panic msg=Panicked", "panic line")
+
+	tlm.Log(ctx).Fatal("Exited")
+	util.AssertEqual(t, exited, true, "exited")
+	push = nextLokiPush(t, pushes)
+	util.AssertEqual(t, push.streams["{}"][0].line, "level=fatal msg=Exited", "fatal line")
+}
@@ -0,0 +1,333 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// A minimal MessagePack encoder and decoder, only what's needed by the Fluent Forward protocol
+
+func writeMsgpackArrayHeader(b *bytes.Buffer, length int) {
+	switch {
+	case length < 16:
+		b.WriteByte(0x90 | byte(length))
+	case length <= math.MaxUint16:
+		b.WriteByte(0xdc)
+		binary.Write(b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(0xdd)
+		binary.Write(b, binary.BigEndian, uint32(length))
+	}
+}
+
+func writeMsgpackMapHeader(b *bytes.Buffer, length int) {
+	switch {
+	case length < 16:
+		b.WriteByte(0x80 | byte(length))
+	case length <= math.MaxUint16:
+		b.WriteByte(0xde)
+		binary.Write(b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(0xdf)
+		binary.Write(b, binary.BigEndian, uint32(length))
+	}
+}
+
+func writeMsgpackString(b *bytes.Buffer, value string) {
+	length := len(value)
+	switch {
+	case length < 32:
+		b.WriteByte(0xa0 | byte(length))
+	case length <= math.MaxUint8:
+		b.WriteByte(0xd9)
+		b.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		b.WriteByte(0xda)
+		binary.Write(b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(0xdb)
+		binary.Write(b, binary.BigEndian, uint32(length))
+	}
+	b.WriteString(value)
+}
+
+func writeMsgpackBinary(b *bytes.Buffer, value []byte) {
+	length := len(value)
+	switch {
+	case length <= math.MaxUint8:
+		b.WriteByte(0xc4)
+		b.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		b.WriteByte(0xc5)
+		binary.Write(b, binary.BigEndian, uint16(length))
+	default:
+		b.WriteByte(0xc6)
+		binary.Write(b, binary.BigEndian, uint32(length))
+	}
+	b.Write(value)
+}
+
+func writeMsgpackInt(b *bytes.Buffer, value int64) {
+	switch {
+	case value >= 0:
+		writeMsgpackUint(b, uint64(value))
+	case value >= -32:
+		b.WriteByte(byte(value))
+	default:
+		b.WriteByte(0xd3)
+		binary.Write(b, binary.BigEndian, value)
+	}
+}
+
+func writeMsgpackUint(b *bytes.Buffer, value uint64) {
+	switch {
+	case value < 128:
+		b.WriteByte(byte(value))
+	case value <= math.MaxUint8:
+		b.WriteByte(0xcc)
+		b.WriteByte(byte(value))
+	case value <= math.MaxUint16:
+		b.WriteByte(0xcd)
+		binary.Write(b, binary.BigEndian, uint16(value))
+	case value <= math.MaxUint32:
+		b.WriteByte(0xce)
+		binary.Write(b, binary.BigEndian, uint32(value))
+	default:
+		b.WriteByte(0xcf)
+		binary.Write(b, binary.BigEndian, value)
+	}
+}
+
+// Fluent's EventTime extension, type 0 with seconds and nanoseconds
+func writeMsgpackEventTime(b *bytes.Buffer, value time.Time) {
+	b.WriteByte(0xd7)
+	b.WriteByte(0x00)
+	binary.Write(b, binary.BigEndian, uint32(value.Unix()))
+	binary.Write(b, binary.BigEndian, uint32(value.Nanosecond()))
+}
+
+func writeMsgpackMap(b *bytes.Buffer, values map[string]any) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writeMsgpackMapHeader(b, len(keys))
+	for _, key := range keys {
+		writeMsgpackString(b, key)
+		writeMsgpackValue(b, values[key])
+	}
+}
+
+func writeMsgpackValue(b *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case nil:
+		b.WriteByte(0xc0)
+	case bool:
+		if v {
+			b.WriteByte(0xc3)
+		} else {
+			b.WriteByte(0xc2)
+		}
+	case int:
+		writeMsgpackInt(b, int64(v))
+	case int8:
+		writeMsgpackInt(b, int64(v))
+	case int16:
+		writeMsgpackInt(b, int64(v))
+	case int32:
+		writeMsgpackInt(b, int64(v))
+	case int64:
+		writeMsgpackInt(b, v)
+	case uint:
+		writeMsgpackUint(b, uint64(v))
+	case uint8:
+		writeMsgpackUint(b, uint64(v))
+	case uint16:
+		writeMsgpackUint(b, uint64(v))
+	case uint32:
+		writeMsgpackUint(b, uint64(v))
+	case uint64:
+		writeMsgpackUint(b, v)
+	case float32:
+		b.WriteByte(0xca)
+		binary.Write(b, binary.BigEndian, math.Float32bits(v))
+	case float64:
+		b.WriteByte(0xcb)
+		binary.Write(b, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeMsgpackString(b, v)
+	case []byte:
+		writeMsgpackBinary(b, v)
+	case time.Time:
+		writeMsgpackString(b, v.Format(time.RFC3339Nano))
+	case error:
+		writeMsgpackString(b, v.Error())
+	case []any:
+		writeMsgpackArrayHeader(b, len(v))
+		for _, item := range v {
+			writeMsgpackValue(b, item)
+		}
+	case util.Fields:
+		writeMsgpackMap(b, v)
+	case map[string]any:
+		writeMsgpackMap(b, v)
+	case fmt.Stringer:
+		writeMsgpackString(b, v.String())
+	default:
+		writeMsgpackString(b, fmt.Sprint(v))
+	}
+}
+
+// Read a single value. Extension types are skipped and returned as nil
+func readMsgpackValue(r *bufio.Reader) (any, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return readMsgpackMap(r, int(code&0x0f))
+	case code&0xf0 == 0x90:
+		return readMsgpackArray(r, int(code&0x0f))
+	case code&0xe0 == 0xa0:
+		return readMsgpackBytes(r, int(code&0x1f), true)
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		length, err := readMsgpackLength(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, length, code == 0xd9)
+	case 0xc5, 0xda:
+		length, err := readMsgpackLength(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, length, code == 0xda)
+	case 0xc6, 0xdb:
+		length, err := readMsgpackLength(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, length, code == 0xdb)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		value, err := readMsgpackLength(r, 1<<(code-0xcc))
+		return int64(value), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		value, err := readMsgpackLength(r, size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign extend
+		shift := 64 - 8*size
+		return int64(value) << shift >> shift, nil
+	case 0xca:
+		value, err := readMsgpackLength(r, 4)
+		return float64(math.Float32frombits(uint32(value))), err
+	case 0xcb:
+		value, err := readMsgpackLength(r, 8)
+		return math.Float64frombits(uint64(value)), err
+	case 0xdc, 0xdd:
+		length, err := readMsgpackLength(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, length)
+	case 0xde, 0xdf:
+		length, err := readMsgpackLength(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, length)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		// fixext, the type byte then the data
+		_, err := r.Discard(1 + 1<<(code-0xd4))
+		return nil, err
+	case 0xc7, 0xc8, 0xc9:
+		length, err := readMsgpackLength(r, 1<<(code-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = r.Discard(1 + length)
+		return nil, err
+	}
+	return nil, fmt.Errorf("unknown msgpack type: 0x%x", code)
+}
+
+func readMsgpackLength(r *bufio.Reader, size int) (int, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return int(value), nil
+}
+
+func readMsgpackBytes(r *bufio.Reader, length int, str bool) (any, error) {
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if str {
+		return string(data), nil
+	}
+	return data, nil
+}
+
+func readMsgpackArray(r *bufio.Reader, length int) (any, error) {
+	values := make([]any, 0, length)
+	for i := 0; i < length; i++ {
+		value, err := readMsgpackValue(r)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func readMsgpackMap(r *bufio.Reader, length int) (any, error) {
+	values := make(map[string]any, length)
+	for i := 0; i < length; i++ {
+		key, err := readMsgpackValue(r)
+		if err != nil {
+			return nil, err
+		}
+		keyStr, ok := key.(string)
+		if !ok {
+			return nil, errors.New("msgpack map keys must be strings")
+		}
+		if values[keyStr], err = readMsgpackValue(r); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,224 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Sinks receive every log entry. They can be used as the logger itself (SinkLogType) or as additional outputs for any other logger
+type Sink interface {
+	Write(entry *Entry) error
+	Close() error
+}
+
+// Writes formatted entries to an io.Writer. Used for the Output of SinkLogType
+type writerSink struct {
+	lock      sync.Mutex
+	output    io.Writer
+	formatter EntryFormatter
+}
+
+func newWriterSink(output io.Writer, formatter Formatter, clock util.Clock) (*writerSink, error) {
+	sink := &writerSink{
+		output: output,
+	}
+	switch formatter.Type {
+	case DefaultFormat:
+		formatter.Type = LogfmtFormat
+		sink.formatter = newLogfmtFormatter(formatter)
+	default:
+		entryFormatter, err := formatter.entryFormatter(output, clock)
+		if err != nil {
+			return nil, err
+		}
+		sink.formatter = entryFormatter
+	}
+	return sink, nil
+}
+
+func (w *writerSink) Write(entry *Entry) error {
+	data, err := w.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	_, err = w.output.Write(data)
+	return err
+}
+
+// The output isn't owned by the sink, so it isn't closed
+func (w *writerSink) Close() error {
+	return nil
+}
+
+// Syslog severities, also used by GELF and journald
+const (
+	severityEmergency = iota
+	severityAlert
+	severityCritical
+	severityError
+	severityWarning
+	severityNotice
+	severityInformational
+	severityDebug
+)
+
+func syslogSeverity(level LogLevel) int {
+	switch level {
+	case TraceLevel, DebugLevel:
+		return severityDebug
+	case WarnLevel:
+		return severityWarning
+	case ErrorLevel:
+		return severityError
+	case PanicLevel, FatalLevel:
+		return severityCritical
+	}
+	return severityInformational
+}
+
+// Writes to sinks in addition to another logger. Sinks are written to first, as panic and fatal logs won't return from the
+// primary logger. The Before hooks run once, for the sinks, and the primary logger is given the result
+type teeLogger struct {
+	primary Logger
+	sinks   *entryLogger
+}
+
+func (t *teeLogger) testExitFunc(exitHandler func(int)) bool {
+	type InternalTestingExitHandler interface {
+		testExitFunc(exitHandler func(int)) bool
+	}
+	if v, ok := t.primary.(InternalTestingExitHandler); ok {
+		return v.testExitFunc(exitHandler)
+	}
+	return false
+}
+
+// Write to the sinks, and get the primary logger to write the same entry with
+func (t *teeLogger) write(level LogLevel, msg string) Logger {
+	entry, hooked := t.sinks.write(level, msg)
+	if !hooked {
+		return t.primary
+	}
+	return withHookResult(t.primary, &hookResult{level: level, entry: entry})
+}
+
+// Write a panic or fatal log to the sinks and flush them, as the primary logger won't return
+func (t *teeLogger) writeAndFlush(level LogLevel, msg string) Logger {
+	primary := t.write(level, msg)
+	t.sinks.flush()
+	return primary
+}
+
+func (t *teeLogger) WithCallerSkip(skip int) Logger {
+	return &teeLogger{primary: WithCallerSkip(t.primary, skip), sinks: t.sinks.WithCallerSkip(skip).(*entryLogger)}
+}
+
+// The collector scope is only for the sinks, so the primary logger doesn't write it
+func (t *teeLogger) WithField(key string, value any) Logger {
+	if key == CollectorScopeKey {
+		return &teeLogger{primary: t.primary, sinks: t.sinks.derive(util.Fields{key: value})}
+	}
+	return &teeLogger{primary: t.primary.WithField(key, value), sinks: t.sinks.derive(util.Fields{key: value})}
+}
+
+func (t *teeLogger) WithFields(fields util.Fields) Logger {
+	primaryFields := fields
+	if _, ok := fields[CollectorScopeKey]; ok {
+		primaryFields = withoutCollectorScope(fields)
+	}
+	return &teeLogger{primary: t.primary.WithFields(primaryFields), sinks: t.sinks.derive(fields)}
+}
+
+func (t *teeLogger) WithError(err error) Logger {
+	return &teeLogger{primary: t.primary.WithError(err), sinks: t.sinks.WithError(err).(*entryLogger)}
+}
+
+func (t *teeLogger) V(level int) Logger {
+	return &teeLogger{primary: t.primary.V(level), sinks: t.sinks.V(level).(*entryLogger)}
+}
+
+func (t *teeLogger) Log(level LogLevel, args ...any) {
+	logAtLevel(t, level, args...)
+}
+func (t *teeLogger) Logf(level LogLevel, format string, args ...any) {
+	logfAtLevel(t, level, format, args...)
+}
+func (t *teeLogger) Enabled(level LogLevel) bool {
+	return t.primary.Enabled(level) || t.sinks.Enabled(level)
+}
+
+func (t *teeLogger) Tracef(format string, args ...any) {
+	t.write(TraceLevel, fmt.Sprintf(format, args...)).Tracef(format, args...)
+}
+func (t *teeLogger) Trace(args ...any) {
+	t.write(TraceLevel, fmt.Sprint(args...)).Trace(args...)
+}
+func (t *teeLogger) Traceln(args ...any) {
+	t.write(TraceLevel, sprintln(args...)).Traceln(args...)
+}
+
+func (t *teeLogger) Debugf(format string, args ...any) {
+	t.write(DebugLevel, fmt.Sprintf(format, args...)).Debugf(format, args...)
+}
+func (t *teeLogger) Debug(args ...any) {
+	t.write(DebugLevel, fmt.Sprint(args...)).Debug(args...)
+}
+func (t *teeLogger) Debugln(args ...any) {
+	t.write(DebugLevel, sprintln(args...)).Debugln(args...)
+}
+
+func (t *teeLogger) Infof(format string, args ...any) {
+	t.write(InfoLevel, fmt.Sprintf(format, args...)).Infof(format, args...)
+}
+func (t *teeLogger) Info(args ...any) {
+	t.write(InfoLevel, fmt.Sprint(args...)).Info(args...)
+}
+func (t *teeLogger) Infoln(args ...any) {
+	t.write(InfoLevel, sprintln(args...)).Infoln(args...)
+}
+
+func (t *teeLogger) Warnf(format string, args ...any) {
+	t.write(WarnLevel, fmt.Sprintf(format, args...)).Warnf(format, args...)
+}
+func (t *teeLogger) Warn(args ...any) {
+	t.write(WarnLevel, fmt.Sprint(args...)).Warn(args...)
+}
+func (t *teeLogger) Warnln(args ...any) {
+	t.write(WarnLevel, sprintln(args...)).Warnln(args...)
+}
+
+func (t *teeLogger) Errorf(format string, args ...any) {
+	t.write(ErrorLevel, fmt.Sprintf(format, args...)).Errorf(format, args...)
+}
+func (t *teeLogger) Error(args ...any) {
+	t.write(ErrorLevel, fmt.Sprint(args...)).Error(args...)
+}
+func (t *teeLogger) Errorln(args ...any) {
+	t.write(ErrorLevel, sprintln(args...)).Errorln(args...)
+}
+
+func (t *teeLogger) Panicf(format string, args ...any) {
+	t.writeAndFlush(PanicLevel, fmt.Sprintf(format, args...)).Panicf(format, args...)
+}
+func (t *teeLogger) Panic(args ...any) {
+	t.writeAndFlush(PanicLevel, fmt.Sprint(args...)).Panic(args...)
+}
+func (t *teeLogger) Panicln(args ...any) {
+	t.writeAndFlush(PanicLevel, sprintln(args...)).Panicln(args...)
+}
+
+func (t *teeLogger) Fatalf(format string, args ...any) {
+	t.writeAndFlush(FatalLevel, fmt.Sprintf(format, args...)).Fatalf(format, args...)
+}
+func (t *teeLogger) Fatal(args ...any) {
+	t.writeAndFlush(FatalLevel, fmt.Sprint(args...)).Fatal(args...)
+}
+func (t *teeLogger) Fatalln(args ...any) {
+	t.writeAndFlush(FatalLevel, sprintln(args...)).Fatalln(args...)
+}
@@ -0,0 +1,71 @@
+package logging_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+func startSinkLogger(t *testing.T, sink logging.Sink, setup func(*logging.TLMLoggingInitialization)) logging.TLMLogger {
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:  logging.SinkLogType,
+		Sinks: []logging.Sink{sink},
+		Level: logging.DebugLevel,
+	}
+	if setup != nil {
+		setup(inits.Logging)
+	}
+
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+	return tlm.Log(ctx)
+}
+
+func TestSinkLogTypeRequiresSinks(t *testing.T) {
+	_, err := logging.InitLogging(&logging.TLMLoggingInitialization{Type: logging.SinkLogType})
+	util.AssertError(t, err, "no sinks")
+}
+
+// Buffers entries until it's flushed
+type bufferedSink struct {
+	pending int
+	written []string
+}
+
+func (s *bufferedSink) Write(entry *logging.Entry) error {
+	s.pending++
+	return nil
+}
+func (s *bufferedSink) Flush() error {
+	s.written = append(s.written, fmt.Sprintf("flushed %d", s.pending))
+	s.pending = 0
+	return nil
+}
+func (s *bufferedSink) Close() error { return nil }
+
+func TestTeeFlushesSinks(t *testing.T) {
+	sink := new(bufferedSink)
+	var flushedAtExit []string
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:     logging.LogrusLogType,
+		Output:   io.Discard,
+		Sinks:    []logging.Sink{sink},
+		ExitFunc: func(int) { flushedAtExit = append([]string(nil), sink.written...) },
+	}
+	ctx, err := tlm.Startup(inits)
+	util.AssertNoError(t, err, "startup")
+
+	tlm.Log(ctx).Info("Buffered")
+	util.AssertPanic(t, func() { tlm.Log(ctx).Panic("Panic") }, "panic")
+	util.AssertEqual(t, strings.Join(sink.written, ","), "flushed 2", "flushed before panic")
+
+	tlm.Log(ctx).Logf(logging.FatalLevel, "Fatal %d", 1)
+	util.AssertEqual(t, strings.Join(flushedAtExit, ","), "flushed 2,flushed 1", "flushed before exit")
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"encoding/binary"
+)
+
+// A minimal snappy block encoder, what's needed by the Loki sink. Matches are found with a hash table, which doesn't
+// compress as well as the reference implementation but produces valid snappy blocks.
+
+const (
+	snappyMaxBlockSize = 65536
+	snappyMinMatch     = 4
+	snappyHashBits     = 14
+)
+
+func snappyEncode(src []byte) []byte {
+	var header [binary.MaxVarintLen64]byte
+	dst := make([]byte, 0, len(src)+len(src)/6+16)
+	dst = append(dst, header[:binary.PutUvarint(header[:], uint64(len(src)))]...)
+	for len(src) > 0 {
+		block := src
+		if len(block) > snappyMaxBlockSize {
+			block = block[:snappyMaxBlockSize]
+		}
+		dst = snappyEncodeBlock(dst, block)
+		src = src[len(block):]
+	}
+	return dst
+}
+
+func snappyHash(value uint32) uint32 {
+	return (value * 0x1e35a7bd) >> (32 - snappyHashBits)
+}
+
+func snappyEncodeBlock(dst, src []byte) []byte {
+	var table [1 << snappyHashBits]int32
+	for i := range table {
+		table[i] = -1
+	}
+
+	literalStart := 0
+	for i := 0; i+snappyMinMatch <= len(src); {
+		value := binary.LittleEndian.Uint32(src[i:])
+		hash := snappyHash(value)
+		candidate := int(table[hash])
+		table[hash] = int32(i)
+
+		if candidate < 0 || binary.LittleEndian.Uint32(src[candidate:]) != value {
+			i++
+			continue
+		}
+
+		length := snappyMinMatch
+		for i+length < len(src) && src[candidate+length] == src[i+length] {
+			length++
+		}
+		dst = snappyAppendLiteral(dst, src[literalStart:i])
+		dst = snappyAppendCopy(dst, i-candidate, length)
+		i += length
+		literalStart = i
+	}
+	return snappyAppendLiteral(dst, src[literalStart:])
+}
+
+func snappyAppendLiteral(dst, literal []byte) []byte {
+	if len(literal) == 0 {
+		return dst
+	}
+	n := len(literal) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n<<2))
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	default:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	}
+	return append(dst, literal...)
+}
+
+// Copies with a 2 byte offset, which covers a whole block. Lengths over 64 are split
+func snappyAppendCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+		dst = append(dst, byte(n-1)<<2|0x02, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
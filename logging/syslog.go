@@ -0,0 +1,423 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Default SD-ID for fields. 32473 is the enterprise number reserved for documentation
+	DefaultSyslogStructuredDataID = "fields@32473"
+
+	syslogNilValue = "-"
+
+	syslogMaxHostnameLength = 255
+	syslogMaxAppNameLength  = 48
+	syslogMaxProcIDLength   = 128
+	syslogMaxSDNameLength   = 32
+)
+
+var (
+	errSyslogNotConnected = errors.New("not connected to syslog")
+	errSyslogClosed       = errors.New("syslog sink is closed")
+)
+
+// Local syslog sockets, in the order they're tried
+var syslogLocalAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+type SyslogFormat int
+
+const (
+	SyslogRFC5424 SyslogFormat = iota
+	SyslogRFC3164
+)
+
+func (f SyslogFormat) String() string {
+	switch f {
+	case SyslogRFC5424:
+		return "rfc5424"
+	case SyslogRFC3164:
+		return "rfc3164"
+	}
+	return "unknown"
+}
+
+type SyslogFacility int
+
+const (
+	SyslogKern SyslogFacility = iota
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLpr
+	SyslogNews
+	SyslogUucp
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFtp
+	_ // NTP
+	_ // Log audit
+	_ // Log alert
+	_ // Clock daemon
+	SyslogLocal0
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+type SyslogOptions struct {
+	// "unix", "unixgram", "udp", "tcp", or "tls". When empty, the local syslog socket is used
+	Network string
+	Address string
+	// Only used by "tls"
+	TLSConfig *tls.Config
+
+	Format SyslogFormat
+	// Kernel messages can't be sent, so SyslogKern (the zero value) uses SyslogUser
+	Facility SyslogFacility
+	// Default is the name of the program
+	AppName string
+	// Default is the process ID
+	ProcID string
+	// Default is os.Hostname
+	Hostname string
+	// The SD-ID fields are written under, only used by RFC 5424. Default is DefaultSyslogStructuredDataID
+	StructuredDataID string
+
+	// Connecting and each write give up after this long. Default is 5 seconds
+	Timeout time.Duration
+}
+
+// Writes entries to syslog. RFC 5424 messages have fields written as structured data, RFC 3164 messages have them appended to the message
+type SyslogSink struct {
+	options SyslogOptions
+
+	lock   sync.Mutex
+	conn   net.Conn
+	stream bool
+	// Local syslog daemons expect newline framing on stream sockets, instead of octet counting
+	local  bool
+	closed bool
+}
+
+func NewSyslogSink(options SyslogOptions) (*SyslogSink, error) {
+	switch options.Network {
+	case "":
+		if options.Address != "" {
+			return nil, errors.New("'Network' must be set when 'Address' is set")
+		}
+	case "unix", "unixgram", "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	case "tls":
+		if options.TLSConfig == nil {
+			options.TLSConfig = &tls.Config{}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported network for syslog: %s", options.Network)
+	}
+	if options.Network != "" && options.Address == "" {
+		return nil, errors.New("'Address' must be set")
+	}
+	if options.Format != SyslogRFC5424 && options.Format != SyslogRFC3164 {
+		return nil, fmt.Errorf("unknown syslog format: %d", options.Format)
+	}
+	if options.Facility == SyslogKern {
+		options.Facility = SyslogUser
+	}
+	if options.Facility < SyslogKern || options.Facility > SyslogLocal7 {
+		return nil, fmt.Errorf("unknown syslog facility: %d", options.Facility)
+	}
+	if options.AppName == "" {
+		options.AppName = filepath.Base(os.Args[0])
+	}
+	if options.ProcID == "" {
+		options.ProcID = strconv.Itoa(os.Getpid())
+	}
+	if options.Hostname == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		options.Hostname = host
+	}
+	if options.StructuredDataID == "" {
+		options.StructuredDataID = DefaultSyslogStructuredDataID
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if len(options.StructuredDataID) > syslogMaxSDNameLength || strings.IndexFunc(options.StructuredDataID, invalidSyslogNameRune) >= 0 {
+		return nil, fmt.Errorf("invalid structured data ID: %s", options.StructuredDataID)
+	}
+
+	sink := &SyslogSink{options: options}
+	if err := sink.connect(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *SyslogSink) connect() error {
+	var err error
+	dialer := &net.Dialer{Timeout: s.options.Timeout}
+	switch s.options.Network {
+	case "":
+		for _, address := range syslogLocalAddresses {
+			for _, network := range []string{"unixgram", "unix"} {
+				if s.conn, err = dialer.Dial(network, address); err == nil {
+					s.stream = network == "unix"
+					s.local = true
+					return nil
+				}
+			}
+		}
+		s.conn = nil
+		return errors.New("unable to connect to local syslog")
+	case "tls":
+		s.conn, err = tls.DialWithDialer(dialer, "tcp", s.options.Address, s.options.TLSConfig)
+	default:
+		s.conn, err = dialer.Dial(s.options.Network, s.options.Address)
+	}
+	if err != nil {
+		s.conn = nil
+		return err
+	}
+	s.stream = !strings.HasPrefix(s.options.Network, "udp") && s.options.Network != "unixgram"
+	s.local = s.options.Network == "unix"
+	return nil
+}
+
+func (s *SyslogSink) priority(level LogLevel) int {
+	return int(s.options.Facility)*8 + syslogSeverity(level)
+}
+
+// Header values must be printable ASCII without spaces
+func syslogHeaderValue(value string, maxLength int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if value == "" {
+		return syslogNilValue
+	}
+	if len(value) > maxLength {
+		value = value[:maxLength]
+	}
+	return value
+}
+
+func invalidSyslogNameRune(r rune) bool {
+	return r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"'
+}
+
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if invalidSyslogNameRune(r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "_"
+	}
+	if len(name) > syslogMaxSDNameLength {
+		name = name[:syslogMaxSDNameLength]
+	}
+	return name
+}
+
+// Get a param name that isn't used yet, as different field names can be the same once made valid
+func uniqueSyslogParamName(name string, params map[string]string) string {
+	name = syslogParamName(name)
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := params[unique]; !ok {
+			return unique
+		}
+		suffix := "_" + strconv.Itoa(i)
+		if len(name)+len(suffix) > syslogMaxSDNameLength {
+			unique = name[:syslogMaxSDNameLength-len(suffix)] + suffix
+		} else {
+			unique = name + suffix
+		}
+	}
+}
+
+func syslogParamValue(value any) string {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		str = ""
+	case string:
+		str = v
+	case error:
+		str = v.Error()
+	case time.Time:
+		str = v.Format(time.RFC3339Nano)
+	default:
+		str = fmt.Sprint(v)
+	}
+
+	var b strings.Builder
+	for _, r := range str {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Format an entry as RFC 5424: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *SyslogSink) formatRFC5424(entry *Entry) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ",
+		s.priority(entry.Level),
+		entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderValue(s.options.Hostname, syslogMaxHostnameLength),
+		syslogHeaderValue(s.options.AppName, syslogMaxAppNameLength),
+		syslogHeaderValue(s.options.ProcID, syslogMaxProcIDLength),
+		syslogNilValue)
+
+	params := make(map[string]string, len(entry.Fields)+3)
+	for _, key := range sortedFieldKeys(entry.Fields) {
+		params[uniqueSyslogParamName(key, params)] = syslogParamValue(entry.Fields[key])
+	}
+	if entry.Caller != nil {
+		for key, value := range map[string]any{"file": entry.Caller.File, "line": entry.Caller.Line, "function": entry.Caller.Function} {
+			if _, ok := params[key]; !ok {
+				params[key] = syslogParamValue(value)
+			}
+		}
+	}
+
+	if len(params) == 0 {
+		b.WriteString(syslogNilValue)
+	} else {
+		keys := make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteByte('[')
+		b.WriteString(s.options.StructuredDataID)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=\"%s\"", key, params[key])
+		}
+		b.WriteByte(']')
+	}
+
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(entry.Message)
+	}
+	return b.Bytes()
+}
+
+// Format an entry as RFC 3164: <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+func (s *SyslogSink) formatRFC3164(entry *Entry) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>%s %s %s[%s]: %s",
+		s.priority(entry.Level),
+		entry.Time.Format(time.Stamp),
+		syslogHeaderValue(s.options.Hostname, syslogMaxHostnameLength),
+		syslogHeaderValue(s.options.AppName, syslogMaxAppNameLength),
+		syslogHeaderValue(s.options.ProcID, syslogMaxProcIDLength),
+		entry.Message)
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteByte(' ')
+		writeLogfmtKey(&b, key)
+		b.WriteByte('=')
+		writeLogfmtValue(&b, entry.Fields[key], time.RFC3339)
+	}
+	return b.Bytes()
+}
+
+func (s *SyslogSink) Write(entry *Entry) error {
+	var message []byte
+	if s.options.Format == SyslogRFC3164 {
+		message = s.formatRFC3164(entry)
+	} else {
+		message = s.formatRFC5424(entry)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return errSyslogClosed
+	}
+
+	err := errSyslogNotConnected
+	if s.conn != nil {
+		err = s.write(message)
+	}
+	if err != nil {
+		// The syslog server may have restarted, try once more
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		if err = s.connect(); err == nil {
+			err = s.write(message)
+		}
+	}
+	return err
+}
+
+func (s *SyslogSink) write(message []byte) error {
+	// The lock is held while writing, so a stalled server can't block logging for long
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.options.Timeout)); err != nil {
+		return err
+	}
+	// Stream connections need to frame messages, datagram connections are one message per packet
+	if !s.stream {
+		_, err := s.conn.Write(message)
+		return err
+	}
+
+	var err error
+	if s.options.Format == SyslogRFC5424 && !s.local {
+		// Octet counting, RFC 5425 and RFC 6587
+		_, err = fmt.Fprintf(s.conn, "%d %s", len(message), message)
+	} else {
+		// Non-transparent framing, RFC 6587. Newlines in the message are escaped so it isn't split
+		message = bytes.ReplaceAll(message, []byte{'\n'}, []byte(`\n`))
+		_, err = s.conn.Write(append(message, '\n'))
+	}
+	return err
+}
+
+func (s *SyslogSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
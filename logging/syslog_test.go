@@ -0,0 +1,389 @@
+package logging_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+var syslogTestTime = time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+func syslogTestOptions(network, address string) logging.SyslogOptions {
+	return logging.SyslogOptions{
+		Network:  network,
+		Address:  address,
+		Facility: logging.SyslogLocal0,
+		AppName:  "tester",
+		ProcID:   "42",
+		Hostname: "host.example",
+	}
+}
+
+// Read a message framed with octet counting
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+	message := make([]byte, size)
+	_, err = io.ReadFull(reader, message)
+	return string(message), err
+}
+
+func acceptSyslogMessage(listener net.Listener, format logging.SyslogFormat) chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var message string
+		if format == logging.SyslogRFC5424 {
+			message, err = readOctetCounted(reader)
+		} else {
+			message, err = reader.ReadString('\n')
+		}
+		if err != nil {
+			message = err.Error()
+		}
+		received <- message
+	}()
+	return received
+}
+
+func waitForSyslogMessage(t *testing.T, received chan string) string {
+	select {
+	case message := <-received:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	return ""
+}
+
+func readSyslogPacket(t *testing.T, conn net.PacketConn) string {
+	packet := make([]byte, 65536)
+	util.AssertNoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)), "deadline")
+	n, _, err := conn.ReadFrom(packet)
+	util.AssertNoError(t, err, "read packet")
+	return string(packet[:n])
+}
+
+func TestSyslogRFC5424(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tlm")
+	util.AssertNoError(t, err, "temp dir")
+	defer os.RemoveAll(dir)
+
+	address := filepath.Join(dir, "log.sock")
+	listener, err := net.ListenPacket("unixgram", address)
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+
+	sink, err := logging.NewSyslogSink(syslogTestOptions("unixgram", address))
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	tests := []struct {
+		name     string
+		entry    logging.Entry
+		expected string
+	}{
+		{
+			name:     "No Fields",
+			entry:    logging.Entry{Time: syslogTestTime, Level: logging.InfoLevel, Message: "Hello syslog"},
+			expected: "<134>1 2020-01-02T03:04:05.000006Z host.example tester 42 - - Hello syslog",
+		},
+		{
+			name: "Fields",
+			entry: logging.Entry{
+				Time:    syslogTestTime,
+				Level:   logging.ErrorLevel,
+				Message: "Failed",
+				Fields: util.Fields{
+					"count":   3,
+					"err":     errors.New("bad \"thing\"]"),
+					"bad key": true,
+				},
+			},
+			expected: "<131>1 2020-01-02T03:04:05.000006Z host.example tester 42 - [fields@32473 bad_key=\"true\" count=\"3\" err=\"bad \\\"thing\\\"\\]\"] Failed",
+		},
+		{
+			name: "Caller",
+			entry: logging.Entry{
+				Time:    syslogTestTime,
+				Level:   logging.TraceLevel,
+				Message: "Traced",
+				Caller:  &runtime.Frame{Function: "main.main", File: "main.go", Line: 12},
+			},
+			expected: "<135>1 2020-01-02T03:04:05.000006Z host.example tester 42 - [fields@32473 file=\"main.go\" function=\"main.main\" line=\"12\"] Traced",
+		},
+		{
+			name: "Colliding Names",
+			entry: logging.Entry{
+				Time:    syslogTestTime,
+				Level:   logging.InfoLevel,
+				Message: "Collided",
+				Fields: util.Fields{
+					"a b":                   1,
+					"a_b":                   2,
+					strings.Repeat("x", 33): 3,
+					strings.Repeat("x", 40): 4,
+				},
+			},
+			expected: "<134>1 2020-01-02T03:04:05.000006Z host.example tester 42 - [fields@32473 a_b=\"1\" a_b_2=\"2\" " + strings.Repeat("x", 30) + "_2=\"4\" " + strings.Repeat("x", 32) + "=\"3\"] Collided",
+		},
+		{
+			name:     "Fatal",
+			entry:    logging.Entry{Time: syslogTestTime, Level: logging.FatalLevel, Message: "Done"},
+			expected: "<130>1 2020-01-02T03:04:05.000006Z host.example tester 42 - - Done",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			util.AssertNoError(t, sink.Write(&test.entry), "write")
+			util.AssertEqual(t, readSyslogPacket(t, listener), test.expected, "message")
+		})
+	}
+
+	// Closed sinks don't reconnect
+	util.AssertNoError(t, sink.Close(), "close")
+	util.AssertError(t, sink.Write(&logging.Entry{Time: syslogTestTime, Message: "Closed"}), "write after close")
+}
+
+func TestSyslogRFC3164(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+
+	options := syslogTestOptions("udp", listener.LocalAddr().String())
+	options.Format = logging.SyslogRFC3164
+	options.Facility = logging.SyslogKern
+	sink, err := logging.NewSyslogSink(options)
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	util.AssertNoError(t, sink.Write(&logging.Entry{
+		Time:    syslogTestTime,
+		Level:   logging.WarnLevel,
+		Message: "Hello old syslog",
+		Fields:  util.Fields{"myField": "two words", "count": 2},
+	}), "write")
+	util.AssertEqual(t, readSyslogPacket(t, listener), "<12>Jan  2 03:04:05 host.example tester[42]: Hello old syslog count=2 myField=\"two words\"", "message")
+}
+
+func TestSyslogTCP(t *testing.T) {
+	for _, format := range []logging.SyslogFormat{logging.SyslogRFC5424, logging.SyslogRFC3164} {
+		t.Run(format.String(), func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			util.AssertNoError(t, err, "listen")
+			defer listener.Close()
+			received := acceptSyslogMessage(listener, format)
+
+			options := syslogTestOptions("tcp", listener.Addr().String())
+			options.Format = format
+			sink, err := logging.NewSyslogSink(options)
+			util.AssertNoError(t, err, "sink")
+			defer sink.Close()
+
+			logger := startSinkLogger(t, sink, nil)
+			logger.WithField("myField", "value").Info("Over TCP")
+
+			message := waitForSyslogMessage(t, received)
+			util.AssertEqual(t, strings.HasPrefix(message, "<134>"), true, "priority")
+			if format == logging.SyslogRFC5424 {
+				util.AssertContains(t, message, " host.example tester 42 - [fields@32473 myField=\"value\"] Over TCP", "message")
+			} else {
+				util.AssertContains(t, message, " host.example tester[42]: Over TCP myField=value\n", "message")
+			}
+		})
+	}
+}
+
+func TestSyslogUnixStream(t *testing.T) {
+	tests := []struct {
+		format   logging.SyslogFormat
+		expected string
+	}{
+		{
+			format:   logging.SyslogRFC5424,
+			expected: "<134>1 2020-01-02T03:04:05.000006Z host.example tester 42 - - First\\nSecond\n",
+		},
+		{
+			format:   logging.SyslogRFC3164,
+			expected: "<134>Jan  2 03:04:05 host.example tester[42]: First\\nSecond\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.format.String(), func(t *testing.T) {
+			address := filepath.Join(t.TempDir(), "log.sock")
+			listener, err := net.Listen("unix", address)
+			util.AssertNoError(t, err, "listen")
+			defer listener.Close()
+			received := make(chan string, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					received <- err.Error()
+					return
+				}
+				defer conn.Close()
+				message, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					message = err.Error()
+				}
+				received <- message
+			}()
+
+			// Local sockets are newline framed whatever the format, and newlines in the message don't split it
+			options := syslogTestOptions("unix", address)
+			options.Format = test.format
+			sink, err := logging.NewSyslogSink(options)
+			util.AssertNoError(t, err, "sink")
+			defer sink.Close()
+
+			util.AssertNoError(t, sink.Write(&logging.Entry{Time: syslogTestTime, Level: logging.InfoLevel, Message: "First\nSecond"}), "write")
+			util.AssertEqual(t, waitForSyslogMessage(t, received), test.expected, "message")
+		})
+	}
+}
+
+func TestSyslogWriteTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+	stalled := make(chan net.Conn, 2)
+	go func() {
+		// Accept connections but never read from them
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			stalled <- conn
+		}
+	}()
+	defer func() {
+		for len(stalled) > 0 {
+			(<-stalled).Close()
+		}
+	}()
+
+	options := syslogTestOptions("tcp", listener.Addr().String())
+	options.Timeout = 20 * time.Millisecond
+	sink, err := logging.NewSyslogSink(options)
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	// Larger than the socket buffers, so the write and the retry on a new connection both time out instead of blocking
+	start := time.Now()
+	util.AssertError(t, sink.Write(&logging.Entry{Time: syslogTestTime, Level: logging.InfoLevel, Message: strings.Repeat("x", 32<<20)}), "write")
+	util.AssertEqual(t, time.Since(start) < 2*time.Second, true, "timed out")
+}
+
+func createTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	util.AssertNoError(t, err, "key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	util.AssertNoError(t, err, "certificate")
+	return tls.Certificate{Certificate: [][]byte{cert}, PrivateKey: key}
+}
+
+func TestSyslogTLS(t *testing.T) {
+	cert := createTestCertificate(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	util.AssertNoError(t, err, "listen")
+	defer listener.Close()
+	received := acceptSyslogMessage(listener, logging.SyslogRFC5424)
+
+	roots := x509.NewCertPool()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	util.AssertNoError(t, err, "parse certificate")
+	roots.AddCert(parsed)
+
+	options := syslogTestOptions("tls", listener.Addr().String())
+	options.TLSConfig = &tls.Config{RootCAs: roots}
+	sink, err := logging.NewSyslogSink(options)
+	util.AssertNoError(t, err, "sink")
+	defer sink.Close()
+
+	util.AssertNoError(t, sink.Write(&logging.Entry{Time: syslogTestTime, Level: logging.DebugLevel, Message: "Secure"}), "write")
+	util.AssertEqual(t, waitForSyslogMessage(t, received), "<135>1 2020-01-02T03:04:05.000006Z host.example tester 42 - - Secure", "message")
+}
+
+func TestSyslogOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options logging.SyslogOptions
+	}{
+		{
+			name:    "No Address",
+			options: logging.SyslogOptions{Network: "udp"},
+		},
+		{
+			name:    "No Network",
+			options: logging.SyslogOptions{Address: "127.0.0.1:514"},
+		},
+		{
+			name:    "Unknown Network",
+			options: logging.SyslogOptions{Network: "carrier-pigeon", Address: "127.0.0.1:514"},
+		},
+		{
+			name:    "Unknown Format",
+			options: logging.SyslogOptions{Network: "udp", Address: "127.0.0.1:514", Format: 5},
+		},
+		{
+			name:    "Unknown Facility",
+			options: logging.SyslogOptions{Network: "udp", Address: "127.0.0.1:514", Facility: 50},
+		},
+		{
+			name:    "Invalid SD-ID",
+			options: logging.SyslogOptions{Network: "udp", Address: "127.0.0.1:514", StructuredDataID: "bad id"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := logging.NewSyslogSink(test.options)
+			util.AssertError(t, err, fmt.Sprintf("options: %+v", test.options))
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package logging
+
+import "os"
+
+func isTerminalFile(file *os.File) bool {
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package logging
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func isTerminalFile(file *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(file.Fd()), ioctlReadTermios)
+	return err == nil
+}
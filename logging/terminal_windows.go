@@ -0,0 +1,17 @@
+package logging
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func isTerminalFile(file *os.File) bool {
+	handle := windows.Handle(file.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	// Colors need escape sequences to be processed
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
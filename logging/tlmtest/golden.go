@@ -0,0 +1,204 @@
+// Test helpers for code that logs with TLM
+package tlmtest
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+)
+
+// Set to true to rewrite golden files with the current output, for test packages that don't have an update flag
+const UpdateEnv = "TLM_UPDATE_GOLDEN"
+
+// Golden files are rewritten when the test package defines an update flag that's set, or UpdateEnv is set. The flag
+// isn't defined here, as test packages often have their own
+func updating() bool {
+	if f := flag.Lookup("update"); f != nil {
+		if update, err := strconv.ParseBool(f.Value.String()); err == nil && update {
+			return true
+		}
+	}
+	update, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return update
+}
+
+// Replaces volatile values in captured output so it can be compared between runs
+type Scrubber func(output string) string
+
+// Replace every match of the pattern. The replacement can use regexp.Expand references like $1
+func RegexpScrubber(pattern, replacement string) Scrubber {
+	re := regexp.MustCompile(pattern)
+	return func(output string) string {
+		return re.ReplaceAllString(output, replacement)
+	}
+}
+
+// Scrubs timestamps, caller file paths and line numbers, durations, and UUIDs
+func DefaultScrubbers() []Scrubber {
+	return []Scrubber{
+		RegexpScrubber(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`, "<TIME>"),
+		RegexpScrubber(`(?:[A-Za-z]:)?[^\s"=]*[/\\]([^/\\\s"=]+\.go):\d+`, "$1:<LINE>"),
+		RegexpScrubber(`\b(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+\b`, "<DURATION>"),
+		RegexpScrubber(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`, "<UUID>"),
+	}
+}
+
+type GoldenOptions struct {
+	// Defaults to testdata/<test name>.golden. Subtests are put in subdirectories
+	Path string
+
+	// Defaults to JsonFormat
+	Formatter logging.Formatter
+	// Change the logging initialization before TLM starts. By default SinkLogType is used, at TraceLevel
+	Setup func(*logging.TLMLoggingInitialization)
+
+	// Applied after the DefaultScrubbers
+	Scrubbers          []Scrubber
+	NoDefaultScrubbers bool
+}
+
+func (o GoldenOptions) path(t testing.TB) string {
+	if o.Path != "" {
+		return o.Path
+	}
+	return filepath.Join("testdata", filepath.FromSlash(t.Name())+".golden")
+}
+
+func (o GoldenOptions) scrub(output string) string {
+	if !o.NoDefaultScrubbers {
+		for _, scrubber := range DefaultScrubbers() {
+			output = scrubber(output)
+		}
+	}
+	for _, scrubber := range o.Scrubbers {
+		output = scrubber(output)
+	}
+	return output
+}
+
+// Output may be written from any goroutine
+type syncBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (s *syncBuffer) Write(data []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.buffer.Write(data)
+}
+
+func (s *syncBuffer) Bytes() []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]byte(nil), s.buffer.Bytes()...)
+}
+
+// Start TLM with its output captured. When the test finishes, the output is compared with the golden file. Run the tests
+// with -update, or with UpdateEnv set, to rewrite the golden files
+func Golden(t testing.TB, options GoldenOptions) context.Context {
+	t.Helper()
+
+	output := new(syncBuffer)
+	inits := new(tlm.TLMInitialization)
+	inits.Logging = &logging.TLMLoggingInitialization{
+		Type:      logging.SinkLogType,
+		Output:    output,
+		Level:     logging.TraceLevel,
+		Formatter: options.Formatter,
+	}
+	if inits.Logging.Formatter.Type == logging.DefaultFormat {
+		inits.Logging.Formatter.Type = logging.JsonFormat
+	}
+	if options.Setup != nil {
+		options.Setup(inits.Logging)
+	}
+
+	ctx, err := tlm.Startup(inits)
+	if err != nil {
+		t.Fatalf("Failed to start TLM: %v", err)
+	}
+	t.Cleanup(func() {
+		CompareGolden(t, output.Bytes(), options)
+	})
+	return ctx
+}
+
+// Scrub the output and compare it with the golden file, or rewrite the golden file when updating
+func CompareGolden(t testing.TB, output []byte, options GoldenOptions) {
+	t.Helper()
+
+	path := options.path(t)
+	actual := options.scrub(string(output))
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file, run with -update to create it: %v", err)
+		return
+	}
+	if string(expected) != actual {
+		t.Errorf("Output doesn't match %s, run with -update if the change is expected:\n%s", path, diffLines(string(expected), actual))
+	}
+}
+
+// A line diff of the golden file and actual output. Lines only in the golden file start with "-", and lines only in the
+// output start with "+"
+func diffLines(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	// Longest common subsequence, so unchanged lines between changes line up
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&diff, "  %s\n", a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || common[i][j+1] >= common[i+1][j]):
+			fmt.Fprintf(&diff, "+ %s\n", b[j])
+			j++
+		default:
+			fmt.Fprintf(&diff, "- %s\n", a[i])
+			i++
+		}
+	}
+	return diff.String()
+}
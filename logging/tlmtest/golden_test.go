@@ -0,0 +1,124 @@
+package tlmtest_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcmaniac25/tlm"
+	"github.com/rcmaniac25/tlm/logging"
+	"github.com/rcmaniac25/tlm/logging/tlmtest"
+	"github.com/rcmaniac25/tlm/util"
+)
+
+// Test packages define their own update flag, which the golden files use
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+func TestGolden(t *testing.T) {
+	ctx := tlmtest.Golden(t, tlmtest.GoldenOptions{
+		Formatter: logging.Formatter{FunctionKey: "~"},
+		Scrubbers: []tlmtest.Scrubber{tlmtest.RegexpScrubber(`req-\d+`, "req-<ID>")},
+	})
+
+	logger := tlm.Log(ctx)
+	logger.WithFields(util.Fields{
+		"request_id": fmt.Sprintf("req-%d", time.Now().UnixNano()),
+		"trace":      "0b7a52c4-4dd1-4c2e-9a3e-6f0c1f1c8e2d",
+		"took":       (1500 * time.Microsecond).String(),
+		"started":    time.Now(),
+	}).Info("Request served")
+	logger.WithField("attempt", 2).Warn("Retrying")
+}
+
+func TestGoldenSubtests(t *testing.T) {
+	for _, format := range []logging.FormatterType{logging.LogfmtFormat, logging.EcsFormat} {
+		t.Run(format.String(), func(t *testing.T) {
+			ctx := tlmtest.Golden(t, tlmtest.GoldenOptions{Formatter: logging.Formatter{Type: format}})
+			tlm.Log(ctx).WithField("user", "bob").Info("Logged in")
+		})
+	}
+}
+
+// Records failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestCompareGolden(t *testing.T) {
+	if *update || os.Getenv(tlmtest.UpdateEnv) != "" {
+		t.Skip("Golden files are being rewritten")
+	}
+
+	path := filepath.Join(t.TempDir(), "compare.golden")
+	util.AssertNoError(t, os.WriteFile(path, []byte("level=info msg=first\nlevel=info msg=second time=<TIME>\nlevel=info msg=third\n"), 0644), "write")
+
+	recorder := &recordingT{TB: t}
+	tlmtest.CompareGolden(recorder, []byte("level=info msg=first\nlevel=info msg=second time=2024-03-01T10:00:00.123Z\nlevel=info msg=third\n"), tlmtest.GoldenOptions{Path: path})
+	util.AssertEqual(t, len(recorder.failures), 0, "scrubbed match")
+
+	tlmtest.CompareGolden(recorder, []byte("level=info msg=first\nlevel=warn msg=changed\nlevel=info msg=third\nlevel=info msg=fourth\n"), tlmtest.GoldenOptions{Path: path})
+	util.AssertEqual(t, len(recorder.failures), 1, "mismatch")
+	util.AssertContains(t, recorder.failures[0], strings.Join([]string{
+		"  level=info msg=first",
+		"+ level=warn msg=changed",
+		"- level=info msg=second time=<TIME>",
+		"  level=info msg=third",
+		"+ level=info msg=fourth",
+	}, "\n"), "diff")
+
+	tlmtest.CompareGolden(recorder, nil, tlmtest.GoldenOptions{Path: filepath.Join(t.TempDir(), "missing.golden")})
+	util.AssertEqual(t, len(recorder.failures), 2, "missing")
+	util.AssertContains(t, recorder.failures[1], "run with -update", "missing message")
+}
+
+func TestCompareGoldenUpdateEnv(t *testing.T) {
+	t.Setenv(tlmtest.UpdateEnv, "true")
+	path := filepath.Join(t.TempDir(), "updated", "env.golden")
+
+	recorder := &recordingT{TB: t}
+	tlmtest.CompareGolden(recorder, []byte("level=info msg=updated time=2024-03-01T10:00:00Z\n"), tlmtest.GoldenOptions{Path: path})
+	util.AssertEqual(t, len(recorder.failures), 0, "failures")
+	written, err := os.ReadFile(path)
+	util.AssertNoError(t, err, "read")
+	util.AssertEqual(t, string(written), "level=info msg=updated time=<TIME>\n", "golden file")
+}
+
+func TestDefaultScrubbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Time", input: "time=2024-03-01T10:00:00Z", expected: "time=<TIME>"},
+		{name: "Time Offset", input: `"time":"2024-03-01T10:00:00.123456-07:00"`, expected: `"time":"<TIME>"`},
+		{name: "Caller", input: "file=/home/me/src/app/server.go:123", expected: "file=server.go:<LINE>"},
+		{name: "Windows Caller", input: `file=C:\src\app\server.go:45`, expected: "file=server.go:<LINE>"},
+		{name: "Durations", input: "took=1.5ms wait=2m30s total=15µs", expected: "took=<DURATION> wait=<DURATION> total=<DURATION>"},
+		{name: "Not Durations", input: "msg=\"5 items\" id=x5s", expected: "msg=\"5 items\" id=x5s"},
+		{name: "UUID", input: "id=0B7A52C4-4dd1-4c2e-9a3e-6f0c1f1c8e2d", expected: "id=<UUID>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := test.input
+			for _, scrubber := range tlmtest.DefaultScrubbers() {
+				output = scrubber(output)
+			}
+			util.AssertEqual(t, output, test.expected, "scrubbed")
+		})
+	}
+}
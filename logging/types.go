@@ -2,6 +2,7 @@ package logging
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/rcmaniac25/tlm/util"
@@ -16,6 +17,8 @@ const (
 	CustomLogType LogType = iota
 
 	LogrusLogType
+	// Only writes to Sinks
+	SinkLogType
 )
 
 func (t LogType) String() string {
@@ -24,12 +27,15 @@ func (t LogType) String() string {
 		return "Custom"
 	case LogrusLogType:
 		return "Logrus"
+	case SinkLogType:
+		return "Sink"
 	}
 	return "unknown"
 }
 
 type LogLevel int
 
+// Values are kept the same as levels are added, so TraceLevel comes last. Compare levels with Severity
 const (
 	// Default log level is logger specific
 	DefaultLevel LogLevel = iota
@@ -40,10 +46,26 @@ const (
 	ErrorLevel
 	PanicLevel
 	FatalLevel
+
+	// Below debug, for wire-level logs that should never show up unless explicitly requested
+	TraceLevel
 )
 
+// Get the order of the level, from TraceLevel (1) to FatalLevel (7). DefaultLevel and unknown levels are 0
+func (t LogLevel) Severity() int {
+	switch t {
+	case TraceLevel:
+		return 1
+	case DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel:
+		return int(t) + 1
+	}
+	return 0
+}
+
 func (t LogLevel) String() string {
 	switch t {
+	case TraceLevel:
+		return "trace"
 	case DebugLevel:
 		return "debug"
 	case InfoLevel:
@@ -68,11 +90,92 @@ type TLMLoggingInitialization struct {
 	Output    io.Writer
 	Level     LogLevel
 	Formatter Formatter
+	// Entries are written to every sink, in addition to the logger
+	Sinks []Sink
+
+	// Time source for entry timestamps. Defaults to util.SystemClock
+	Clock util.Clock
+
+	// klog-style verbosity. Loggers returned by V(n) only log when n <= Verbosity
+	Verbosity int
+
+	// Capture the stack where WithError was called when the error doesn't carry its own stack
+	CaptureErrorStack bool
 
-	//TODO: logger specific variables
+	// Run for every entry, in order. The logger must implement HookLogger
+	Hooks []Hook
+
+	// Called instead of os.Exit by fatal logs, for every logger derived from this one. Loggers that don't implement
+	// ExitFuncLogger write fatal logs as errors before it's called
+	ExitFunc func(code int)
+	// Called instead of panicking by panic logs, with the value the logger panicked with. If it returns, so does the log call
+	PanicFunc func(value any)
+
+	// Set for debug and test builds. Loggers marked with PanicOnlyDebugMode only panic when set
+	DebugMode bool
+
+	// Options for specific loggers, keyed by the logger type name. For builtin loggers, this is LogType.String()
+	// and for custom loggers this is CustomeType. Loggers use GetBackendOptions or LookupBackendOptions to get their options.
+	BackendOptions map[string]any
+}
+
+// Get the clock to use, util.SystemClock if one isn't set
+func (args *TLMLoggingInitialization) clock() util.Clock {
+	if args.Clock == nil {
+		return util.SystemClock
+	}
+	return args.Clock
+}
+
+// Set the options for a specific logger type
+func (args *TLMLoggingInitialization) SetBackendOptions(typeName string, options any) {
+	if args.BackendOptions == nil {
+		args.BackendOptions = make(map[string]any)
+	}
+	args.BackendOptions[typeName] = options
+}
+
+// Get the options for a specific logger type. Options can be set as either T or *T
+func GetBackendOptions[T any](args *TLMLoggingInitialization, typeName string) (T, bool) {
+	var empty T
+	if args == nil {
+		return empty, false
+	}
+	switch options := args.BackendOptions[typeName].(type) {
+	case T:
+		return options, true
+	case *T:
+		if options != nil {
+			return *options, true
+		}
+	}
+	return empty, false
+}
+
+// Same as GetBackendOptions, but returns an error when options are set for the logger type with a type other than T
+// or *T
+func LookupBackendOptions[T any](args *TLMLoggingInitialization, typeName string) (T, bool, error) {
+	options, ok := GetBackendOptions[T](args, typeName)
+	if ok || args == nil {
+		return options, ok, nil
+	}
+	switch value := args.BackendOptions[typeName].(type) {
+	case nil, *T:
+		return options, false, nil
+	default:
+		return options, false, fmt.Errorf("backend options for %s have the wrong type: %T", typeName, value)
+	}
+}
+
+// Loggers that can call a function other than os.Exit for fatal logs. Custom loggers implement this to support
+// TLMLoggingInitialization.ExitFunc. It's called during initialization, so it may change the logger it's called on
+type ExitFuncLogger interface {
+	// Get a logger that calls exitFunc for fatal logs, as do all loggers derived from it
+	WithExitFunc(exitFunc func(int)) Logger
 }
 
 type Logger interface {
+	Tracef(format string, args ...any)
 	Debugf(format string, args ...any)
 	Infof(format string, args ...any)
 	Warnf(format string, args ...any)
@@ -80,6 +183,7 @@ type Logger interface {
 	Panicf(format string, args ...any)
 	Fatalf(format string, args ...any)
 
+	Trace(args ...any)
 	Debug(args ...any)
 	Info(args ...any)
 	Warn(args ...any)
@@ -87,6 +191,7 @@ type Logger interface {
 	Panic(args ...any)
 	Fatal(args ...any)
 
+	Traceln(args ...any)
 	Debugln(args ...any)
 	Infoln(args ...any)
 	Warnln(args ...any)
@@ -97,7 +202,17 @@ type Logger interface {
 	WithField(key string, value any) Logger
 	WithFields(fields util.Fields) Logger
 
-	//TODO: PanicOnlyDebugMode //XXX don't actually implement this. This should be a value passed into WithField(s) and if
+	// Log at a level picked at runtime. Unknown levels are ignored
+	Log(level LogLevel, args ...any)
+	Logf(level LogLevel, format string, args ...any)
+	// Check if a level will be logged, to skip building expensive arguments
+	Enabled(level LogLevel) bool
+
+	// Returns a logger that only logs if level is less than or equal to the initialized Verbosity
+	V(level int) Logger
+
+	// Records the error, it's causes, and stack trace (if it has one) as fields
+	WithError(err error) Logger
 }
 
 type TLMLogger interface {
@@ -54,6 +54,11 @@ func TestLogLevel(t *testing.T) {
 			value:    logging.DefaultLevel,
 			expected: "",
 		},
+		{
+			name:     "Trace",
+			value:    logging.TraceLevel,
+			expected: "trace",
+		},
 		{
 			name:     "Debug",
 			value:    logging.DebugLevel,
@@ -92,3 +97,119 @@ func TestLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestLogLevelSeverity(t *testing.T) {
+	// Values stay the same as levels are added
+	util.AssertEqual(t, int(logging.FatalLevel), 6, "fatal value")
+	util.AssertEqual(t, logging.DefaultLevel.Severity(), 0, "default")
+	util.AssertEqual(t, logging.LogLevel(-1).Severity(), 0, "unknown")
+	levels := []logging.LogLevel{logging.TraceLevel, logging.DebugLevel, logging.InfoLevel, logging.WarnLevel, logging.ErrorLevel, logging.PanicLevel, logging.FatalLevel}
+	for i, level := range levels {
+		util.AssertEqualf(t, level.Severity(), i+1, "%v", level)
+	}
+}
+
+func TestGetBackendOptions(t *testing.T) {
+	type options struct {
+		Value int
+	}
+	tests := []struct {
+		name     string
+		args     *logging.TLMLoggingInitialization
+		expected options
+		exists   bool
+	}{
+		{
+			name:   "No Args",
+			args:   nil,
+			exists: false,
+		},
+		{
+			name:   "No Options",
+			args:   &logging.TLMLoggingInitialization{},
+			exists: false,
+		},
+		{
+			name: "Value",
+			args: &logging.TLMLoggingInitialization{
+				BackendOptions: map[string]any{"MyLogger": options{Value: 10}},
+			},
+			expected: options{Value: 10},
+			exists:   true,
+		},
+		{
+			name: "Pointer",
+			args: &logging.TLMLoggingInitialization{
+				BackendOptions: map[string]any{"MyLogger": &options{Value: 20}},
+			},
+			expected: options{Value: 20},
+			exists:   true,
+		},
+		{
+			name: "Nil Pointer",
+			args: &logging.TLMLoggingInitialization{
+				BackendOptions: map[string]any{"MyLogger": (*options)(nil)},
+			},
+			exists: false,
+		},
+		{
+			name: "Wrong Type",
+			args: &logging.TLMLoggingInitialization{
+				BackendOptions: map[string]any{"MyLogger": 30},
+			},
+			exists: false,
+		},
+		{
+			name: "Wrong Logger",
+			args: &logging.TLMLoggingInitialization{
+				BackendOptions: map[string]any{"OtherLogger": options{Value: 40}},
+			},
+			exists: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := logging.GetBackendOptions[options](tt.args, "MyLogger")
+			util.AssertEqual(t, ok, tt.exists, "exists")
+			util.AssertEqual(t, value, tt.expected, "options")
+		})
+	}
+}
+
+func TestLookupBackendOptions(t *testing.T) {
+	type options struct {
+		Value int
+	}
+	value, ok, err := logging.LookupBackendOptions[options](&logging.TLMLoggingInitialization{
+		BackendOptions: map[string]any{"MyLogger": &options{Value: 10}},
+	}, "MyLogger")
+	util.AssertNoError(t, err, "pointer")
+	util.AssertEqual(t, ok, true, "pointer exists")
+	util.AssertEqual(t, value, options{Value: 10}, "pointer options")
+
+	_, ok, err = logging.LookupBackendOptions[options](&logging.TLMLoggingInitialization{
+		BackendOptions: map[string]any{"MyLogger": (*options)(nil)},
+	}, "MyLogger")
+	util.AssertNoError(t, err, "nil pointer")
+	util.AssertEqual(t, ok, false, "nil pointer exists")
+
+	_, ok, err = logging.LookupBackendOptions[options](nil, "MyLogger")
+	util.AssertNoError(t, err, "no args")
+	util.AssertEqual(t, ok, false, "no args exists")
+
+	_, ok, err = logging.LookupBackendOptions[options](&logging.TLMLoggingInitialization{
+		BackendOptions: map[string]any{"MyLogger": 30},
+	}, "MyLogger")
+	util.AssertError(t, err, "wrong type")
+	util.AssertEqual(t, ok, false, "wrong type exists")
+}
+
+func TestSetBackendOptions(t *testing.T) {
+	args := new(logging.TLMLoggingInitialization)
+	args.SetBackendOptions("MyLogger", 10)
+	args.SetBackendOptions("MyLogger", 20)
+
+	value, ok := logging.GetBackendOptions[int](args, "MyLogger")
+	util.AssertEqual(t, ok, true, "exists")
+	util.AssertEqual(t, value, 20, "options")
+}
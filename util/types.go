@@ -1,9 +1,33 @@
 package util
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ContextWrapper interface {
 	GetContext() context.Context
 }
 
 type Fields map[string]any
+
+// Source of the current time. Lets tests and replay tools control timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// The clock used when no clock is set. Uses time.Now
+var SystemClock Clock = systemClock{}
+
+// Use a function as a Clock
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}